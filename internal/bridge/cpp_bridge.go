@@ -18,10 +18,37 @@ package bridge
 // Created: 2026-01-21
 
 import (
+	"fmt"
 	"log"
 	"os/exec"
+	"regexp"
+	"strconv"
 )
 
+// "SMTP code: 550" printed by mail/bin/send on failure
+var reSMTPCode = regexp.MustCompile(`SMTP code: (\d+)`)
+
+// MailError mail/bin/send failed, Code is the last smtp reply (0 if none)
+type MailError struct {
+	ExitCode int
+	Code     int
+	Stderr   string
+}
+
+func (e *MailError) Error() string {
+	return fmt.Sprintf("邮件发送失败 (exit %d, smtp %d)", e.ExitCode, e.Code)
+}
+
+// Permanent rejected by the server (bad recipient, message refused...), a failed
+// login may be fixed by the admin so it is not
+func (e *MailError) Permanent() bool {
+	switch e.Code {
+	case 530, 534, 535:
+		return false
+	}
+	return e.Code >= 500
+}
+
 type SendOptions struct {
 	SMTPServer  string
 	Account     string
@@ -31,6 +58,8 @@ type SendOptions struct {
 	Subject     string
 	Body        string
 	Attachments []string
+	// "Key: Value"
+	Headers []string
 }
 
 func SendMail(opts *SendOptions) error {
@@ -41,6 +70,8 @@ func SendMail(opts *SendOptions) error {
 		"--from", opts.From,
 		"--to", opts.To,
 		"--subject", opts.Subject,
+		// the delivery queue retries
+		"--retries", "1",
 		opts.Body,
 	}
 
@@ -48,6 +79,10 @@ func SendMail(opts *SendOptions) error {
 		args = append(args, "--attachment", path)
 	}
 
+	for _, header := range opts.Headers {
+		args = append(args, "--header", header)
+	}
+
 	cmd := exec.Command("mail/bin/send", args...)
 
 	_, err := cmd.Output()
@@ -56,7 +91,11 @@ func SendMail(opts *SendOptions) error {
 			exitCode := exitError.ExitCode()
 			exitStr := exitError.Stderr
 			log.Printf("C++程序报错 (Exit Code %d): %s", exitCode, string(exitStr))
-			return err
+			mailErr := &MailError{ExitCode: exitCode, Stderr: string(exitStr)}
+			if m := reSMTPCode.FindSubmatch(exitStr); m != nil {
+				mailErr.Code, _ = strconv.Atoi(string(m[1]))
+			}
+			return mailErr
 		}
 		log.Printf("启动失败: %v", err)
 		return err
@@ -26,12 +26,17 @@ import (
 	"os/exec"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type Notice struct {
 	Title string `json:"title"`
 	URL   string `json:"url"`
 	Date  string `json:"date"`
+	// optional, e.g. the registration deadline of a contest
+	Deadline string `json:"deadline,omitempty"`
+	// parsed from Date by the server, nil: unknown
+	PublishedAt *time.Time `json:"-"`
 }
 
 func (n Notice) ContentHash() string {
@@ -0,0 +1,99 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package feed
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type Event struct {
+	UID      string
+	Summary  string
+	URL      string
+	Category string
+	Start    time.Time
+	// only the day is known
+	AllDay bool
+	Stamp  time.Time
+}
+
+// ICal RFC 5545 calendar
+func ICal(name string, events []Event) []byte {
+	var sb strings.Builder
+	line := func(s string) {
+		sb.WriteString(foldLine(s))
+		sb.WriteString("\r\n")
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//NotiCat//NotiCat Server//ZH")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-CALNAME:" + escapeText(name))
+	for _, e := range events {
+		line("BEGIN:VEVENT")
+		line("UID:" + escapeText(e.UID))
+		line("DTSTAMP:" + e.Stamp.UTC().Format("20060102T150405Z"))
+		if e.AllDay {
+			line("DTSTART;VALUE=DATE:" + e.Start.Format("20060102"))
+		} else {
+			line("DTSTART:" + e.Start.UTC().Format("20060102T150405Z"))
+		}
+		line("SUMMARY:" + escapeText(e.Summary))
+		if e.URL != "" {
+			line("URL:" + e.URL)
+			line("DESCRIPTION:" + escapeText(e.URL))
+		}
+		if e.Category != "" {
+			line("CATEGORIES:" + escapeText(e.Category))
+		}
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return []byte(sb.String())
+}
+
+func escapeText(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`, ";", `\;`, ",", `\,`,
+		"\r\n", `\n`, "\n", `\n`, "\r", "",
+	).Replace(s)
+}
+
+// foldLine lines longer than 75 octets go on with a space, never inside a rune
+func foldLine(s string) string {
+	if len(s) <= 75 {
+		return s
+	}
+
+	var sb strings.Builder
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		sb.WriteString(s[:cut])
+		sb.WriteString("\r\n ")
+		s = s[cut:]
+		// the leading space counts
+		limit = 74
+	}
+	sb.WriteString(s)
+	return sb.String()
+}
@@ -0,0 +1,142 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package filestore content-addressed blobs, the name of a file is the sha256 of its content
+package filestore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"noticat/pkg/global"
+)
+
+var ErrInvalidHash = errors.New("无效的文件哈希")
+
+// Root where the blobs live, <root>/<hash[:2]>/<hash>
+func Root() string {
+	return global.FileStoreDir
+}
+
+func validHash(hash string) bool {
+	if len(hash) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(hash)
+	return err == nil
+}
+
+// Path the blob of hash, it may not exist
+func Path(hash string) (string, error) {
+	if !validHash(hash) {
+		return "", ErrInvalidHash
+	}
+	return filepath.Join(Root(), hash[:2], hash), nil
+}
+
+func Exists(hash string) bool {
+	path, err := Path(hash)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(path)
+	return err == nil
+}
+
+// Put copy src into the store, the same content is only kept once
+func Put(src string) (hash string, size int64, err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return "", 0, err
+	}
+	defer in.Close()
+
+	h := sha256.New()
+	size, err = io.Copy(h, in)
+	if err != nil {
+		return "", 0, err
+	}
+	hash = hex.EncodeToString(h.Sum(nil))
+
+	dst, _ := Path(hash)
+	if _, err := os.Stat(dst); err == nil {
+		return hash, size, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return "", 0, err
+	}
+
+	// hard link when possible, else copy through a temp file so a blob is never half written
+	if err := os.Link(src, dst); err == nil {
+		return hash, size, nil
+	}
+	if _, err := in.Seek(0, io.SeekStart); err != nil {
+		return "", 0, err
+	}
+	if err := copyAtomic(in, dst); err != nil {
+		return "", 0, fmt.Errorf("写入文件存储失败: %w", err)
+	}
+	return hash, size, nil
+}
+
+// CopyOut place the blob at dst (hard link, or a copy)
+func CopyOut(hash string, dst string) error {
+	src, err := Path(hash)
+	if err != nil {
+		return err
+	}
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	return copyAtomic(in, dst)
+}
+
+func Remove(hash string) error {
+	path, err := Path(hash)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func copyAtomic(in io.Reader, dst string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".tmp_")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(tmp, in); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
@@ -0,0 +1,317 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+
+	"noticat/internal/model"
+	"noticat/internal/notifier"
+	"noticat/internal/service"
+	"noticat/pkg/global"
+)
+
+func addressCodeKey(userID uint, email string) string {
+	return fmt.Sprintf("code:address:%d:%s", userID, email)
+}
+
+// checkNewAddress the address must not be the account email or added already
+func checkNewAddress(c *gin.Context, userID uint, email string) bool {
+	var user model.User
+	if err := global.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库查询异常"})
+		return false
+	}
+	if strings.EqualFold(user.Email, email) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该邮箱已是账户邮箱"})
+		return false
+	}
+
+	var count int64
+	if err := global.DB.Model(&model.UserAddress{}).Where("user_id = ? AND email = ?", userID, email).Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库查询异常"})
+		return false
+	}
+	if count > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该邮箱已添加"})
+		return false
+	}
+	return true
+}
+
+// SendAddressCodeHandler POST /api/address/code, same flow as SendCodeHandler
+func SendAddressCodeHandler(c *gin.Context) {
+	reqCtx := c.Request.Context()
+	var input struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "邮箱格式不正确"})
+		return
+	}
+
+	userIDVal, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+	var userID uint
+	if val, ok := userIDVal.(float64); ok {
+		userID = uint(val)
+	} else if val, ok := userIDVal.(uint); ok {
+		userID = val
+	} else {
+		log.Printf("实际类型是: %T", userIDVal)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "身份类型错误"})
+		return
+	}
+
+	if !checkNewAddress(c, userID, input.Email) {
+		return
+	}
+
+	lockKey := "lock:send_code:" + input.Email
+
+	locked, _ := global.RDB.Exists(reqCtx, lockKey).Result()
+	if locked > 0 {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "发送太频繁，请一分钟后再试"})
+		return
+	}
+
+	// generate code
+	code := fmt.Sprintf("%06d", rand.New(rand.NewSource(time.Now().UnixNano())).Intn(900000)+100000)
+	codeKey := addressCodeKey(userID, input.Email)
+	err := global.RDB.Set(reqCtx, codeKey, code, 5*time.Minute).Err()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "系统忙，无法生成验证码"})
+		return
+	}
+
+	// set 60 freeze
+	global.RDB.Set(reqCtx, lockKey, "1", time.Minute)
+
+	err = notifier.Send(&notifier.Target{
+		Channel: notifier.ChannelEmail,
+		Address: input.Email,
+	}, &notifier.Message{
+		Subject: "[NotiCat]邮箱验证码：" + code,
+		Body:    "添加通知邮箱验证码" + code,
+	})
+	if err != nil {
+		log.Printf("发送邮件失败: %v", err)
+		global.RDB.Del(reqCtx, codeKey)
+		global.RDB.Del(reqCtx, lockKey)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "邮件发送失败，请检查邮箱地址或稍后再试"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "验证码已发送"})
+}
+
+// CreateAddressHandler POST /api/address {"email", "label", "code"}
+func CreateAddressHandler(c *gin.Context) {
+	reqCtx := c.Request.Context()
+	var input struct {
+		Email string `json:"email" binding:"required,email"`
+		Label string `json:"label" binding:"max=32"`
+		Code  string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format error"})
+		return
+	}
+
+	userIDVal, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+	var userID uint
+	if val, ok := userIDVal.(float64); ok {
+		userID = uint(val)
+	} else if val, ok := userIDVal.(uint); ok {
+		userID = val
+	} else {
+		log.Printf("实际类型是: %T", userIDVal)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "身份类型错误"})
+		return
+	}
+
+	key := addressCodeKey(userID, input.Email)
+	storedCode, err := global.RDB.Get(reqCtx, key).Result()
+	if err == redis.Nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "验证码已过期，请重新获取"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "系统繁忙，请稍后再试"})
+		return
+	}
+	if storedCode != input.Code {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "验证码错误"})
+		return
+	}
+
+	if !checkNewAddress(c, userID, input.Email) {
+		return
+	}
+
+	address := model.UserAddress{
+		UserID: userID,
+		Email:  input.Email,
+		Label:  input.Label,
+	}
+	if err := global.DB.Create(&address).Error; err != nil {
+		log.Printf("保存邮箱地址失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "系统繁忙，请稍后再试"})
+		return
+	}
+
+	global.RDB.Del(reqCtx, key)
+
+	c.JSON(http.StatusOK, gin.H{"message": "添加成功", "address": address})
+}
+
+// GetAddressesHandler GET /api/addresses, id 0 is the account email
+func GetAddressesHandler(c *gin.Context) {
+	userIDVal, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+	var userID uint
+	if val, ok := userIDVal.(float64); ok {
+		userID = uint(val)
+	} else if val, ok := userIDVal.(uint); ok {
+		userID = val
+	} else {
+		log.Printf("实际类型是: %T", userIDVal)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "身份类型错误"})
+		return
+	}
+
+	var user model.User
+	if err := global.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		return
+	}
+
+	var addresses []model.UserAddress
+	if err := global.DB.Where("user_id = ?", userID).Order("id").Find(&addresses).Error; err != nil {
+		log.Printf("查询邮箱地址失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		return
+	}
+
+	items := []gin.H{{"id": 0, "email": user.Email, "label": "账户邮箱"}}
+	for _, a := range addresses {
+		items = append(items, gin.H{"id": a.ID, "email": a.Email, "label": a.Label})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"addresses": items})
+}
+
+func DeleteAddressHandler(c *gin.Context) {
+	userIDVal, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+	var userID uint
+	if val, ok := userIDVal.(float64); ok {
+		userID = uint(val)
+	} else if val, ok := userIDVal.(uint); ok {
+		userID = val
+	} else {
+		log.Printf("实际类型是: %T", userIDVal)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "身份类型错误"})
+		return
+	}
+
+	addressID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || addressID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format error"})
+		return
+	}
+
+	if err := service.DeleteAddress(userID, uint(addressID)); err != nil {
+		if errors.Is(err, service.ErrAddressNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "邮箱地址不存在"})
+			return
+		}
+		log.Printf("删除邮箱地址失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "系统繁忙，请稍后再试"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "删除成功"})
+}
+
+// UpdateSubAddressesHandler PUT /api/subscription/:id/addresses {"address_ids": [0, 3]}
+func UpdateSubAddressesHandler(c *gin.Context) {
+	var input struct {
+		// empty: the account email
+		AddressIDs []uint `json:"address_ids"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format error"})
+		return
+	}
+
+	userIDVal, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+	var userID uint
+	if val, ok := userIDVal.(float64); ok {
+		userID = uint(val)
+	} else if val, ok := userIDVal.(uint); ok {
+		userID = val
+	} else {
+		log.Printf("实际类型是: %T", userIDVal)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "身份类型错误"})
+		return
+	}
+
+	subscriptionID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format error"})
+		return
+	}
+
+	err = service.SetSubscriptionAddresses(userID, uint(subscriptionID), input.AddressIDs)
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, gin.H{"message": "已保存"})
+	case errors.Is(err, service.ErrSubscriptionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "订阅不存在"})
+	case errors.Is(err, service.ErrAddressNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "邮箱地址不存在"})
+	default:
+		log.Printf("更新订阅地址失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "系统繁忙，请稍后再试"})
+	}
+}
@@ -0,0 +1,233 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"noticat/internal/model"
+	"noticat/internal/service"
+	"noticat/pkg/global"
+)
+
+type deliveryResponse struct {
+	ID             uint       `json:"id"`
+	SubscriptionID uint       `json:"subscription_id"`
+	NoticeHash     string     `json:"notice_hash"`
+	Title          string     `json:"title"`
+	Channel        string     `json:"channel"`
+	Address        string     `json:"address"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	LastError      string     `json:"last_error"`
+	CreatedAt      time.Time  `json:"created_at"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+}
+
+func toDeliveryResponse(j *model.DeliveryJob) deliveryResponse {
+	resp := deliveryResponse{
+		ID:             j.ID,
+		SubscriptionID: j.SubscriptionID,
+		NoticeHash:     j.NoticeHash,
+		Title:          j.Title,
+		Channel:        j.Channel,
+		Address:        j.Address,
+		Status:         j.Status,
+		Attempts:       j.Attempts,
+		LastError:      j.LastError,
+		CreatedAt:      j.CreatedAt,
+		DeliveredAt:    j.DeliveredAt,
+	}
+	if j.Status == model.JobPending {
+		next := j.NextAttemptAt
+		resp.NextAttemptAt = &next
+	}
+	return resp
+}
+
+// parseDateParam "2006-01-02" or RFC3339, a bare date as "to" includes the whole day
+func parseDateParam(value string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, service.ServerLocation())
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// GetDeliveriesHandler GET /api/deliveries?subscription_id=&status=&from=&to=&page=&page_size=
+func GetDeliveriesHandler(c *gin.Context) {
+	userIDVal, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+	var userID uint
+	if val, ok := userIDVal.(float64); ok {
+		userID = uint(val)
+	} else if val, ok := userIDVal.(uint); ok {
+		userID = val
+	} else {
+		log.Printf("实际类型是: %T", userIDVal)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "身份类型错误"})
+		return
+	}
+
+	query := global.DB.Model(&model.DeliveryJob{}).Where("user_id = ?", userID)
+
+	if subID := c.Query("subscription_id"); subID != "" {
+		query = query.Where("subscription_id = ?", subID)
+	}
+	if status := c.Query("status"); status != "" {
+		// "failed" is what users call dead
+		if status == "failed" {
+			status = model.JobDead
+		}
+		query = query.Where("status = ?", status)
+	}
+	if from := c.Query("from"); from != "" {
+		t, err := parseDateParam(from, false)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from 格式错误"})
+			return
+		}
+		query = query.Where("created_at >= ?", t)
+	}
+	if to := c.Query("to"); to != "" {
+		t, err := parseDateParam(to, true)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to 格式错误"})
+			return
+		}
+		query = query.Where("created_at < ?", t)
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	page = max(page, 1)
+	pageSize = min(max(pageSize, 1), 100)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		log.Printf("查询投递记录失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		return
+	}
+
+	var jobs []model.DeliveryJob
+	if err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&jobs).Error; err != nil {
+		log.Printf("查询投递记录失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		return
+	}
+
+	items := make([]deliveryResponse, 0, len(jobs))
+	for i := range jobs {
+		items = append(items, toDeliveryResponse(&jobs[i]))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+		"items":     items,
+	})
+}
+
+func GetDeliveryDetailHandler(c *gin.Context) {
+	userIDVal, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+	var userID uint
+	if val, ok := userIDVal.(float64); ok {
+		userID = uint(val)
+	} else if val, ok := userIDVal.(uint); ok {
+		userID = val
+	} else {
+		log.Printf("实际类型是: %T", userIDVal)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "身份类型错误"})
+		return
+	}
+
+	var job model.DeliveryJob
+	if err := global.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&job).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "投递记录不存在"})
+		return
+	}
+
+	var attempts []model.DeliveryAttempt
+	if err := global.DB.Where("job_id = ?", job.ID).Order("id").Find(&attempts).Error; err != nil {
+		log.Printf("查询投递尝试失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"delivery":     toDeliveryResponse(&job),
+		"attempts_log": attempts,
+	})
+}
+
+func RetryDeliveryHandler(c *gin.Context) {
+	userIDVal, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+	var userID uint
+	if val, ok := userIDVal.(float64); ok {
+		userID = uint(val)
+	} else if val, ok := userIDVal.(uint); ok {
+		userID = val
+	} else {
+		log.Printf("实际类型是: %T", userIDVal)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "身份类型错误"})
+		return
+	}
+
+	jobID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format error"})
+		return
+	}
+
+	err = service.RetryDelivery(userID, uint(jobID))
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, gin.H{"message": "已重新加入投递队列"})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "投递记录不存在"})
+	case errors.Is(err, service.ErrNotRetryable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		log.Printf("重试投递失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "系统繁忙，请稍后再试"})
+	}
+}
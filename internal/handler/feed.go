@@ -0,0 +1,264 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"noticat/internal/feed"
+	"noticat/internal/model"
+	"noticat/internal/service"
+	"noticat/pkg/common"
+	"noticat/pkg/global"
+)
+
+const (
+	feedLimit     = 50
+	calendarLimit = 500
+)
+
+func feedURLs(token string) gin.H {
+	return gin.H{
+		"atom_url": fmt.Sprintf("%s/feed/%s.atom", global.PublicURL, token),
+		"rss_url":  fmt.Sprintf("%s/feed/%s.rss", global.PublicURL, token),
+		"ics_url":  fmt.Sprintf("%s/calendar/%s.ics", global.PublicURL, token),
+	}
+}
+
+// FeedHandler GET /feed/:file, file is <token>.atom or <token>.rss
+func FeedHandler(c *gin.Context) {
+	file := c.Param("file")
+	var token, format string
+	switch {
+	case strings.HasSuffix(file, ".atom"):
+		token, format = strings.TrimSuffix(file, ".atom"), "atom"
+	case strings.HasSuffix(file, ".rss"):
+		token, format = strings.TrimSuffix(file, ".rss"), "rss"
+	default:
+		c.JSON(http.StatusNotFound, gin.H{"error": "不支持的格式"})
+		return
+	}
+
+	user, err := service.UserByFeedToken(token)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "订阅源不存在"})
+		return
+	}
+
+	items, err := service.RecentNotices(user.ID, feedLimit)
+	if err != nil {
+		log.Printf("查询订阅源失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		return
+	}
+
+	lastModified, fresh := feedNotModified(c, user, items, format)
+	if fresh {
+		return
+	}
+
+	f := &feed.Feed{
+		ID:      fmt.Sprintf("urn:noticat:user:%d", user.ID),
+		Title:   "NotiCat · " + user.Username,
+		Link:    global.PublicURL,
+		SelfURL: fmt.Sprintf("%s/feed/%s", global.PublicURL, file),
+		Updated: lastModified,
+	}
+	for _, it := range items {
+		content := it.Body
+		if content == "" {
+			content = html.EscapeString(it.Title)
+		}
+		updated := it.ReceivedAt
+		if it.UpdatedAt.After(updated) {
+			updated = it.UpdatedAt
+		}
+		f.Items = append(f.Items, feed.Item{
+			ID:        fmt.Sprintf("urn:noticat:notice:%s:%s", it.Client, it.ContentHash),
+			Title:     it.Title,
+			Link:      it.URL,
+			Category:  it.Client,
+			Content:   content,
+			Published: it.ReceivedAt,
+			Updated:   updated,
+		})
+	}
+
+	var body []byte
+	contentType := "application/atom+xml; charset=utf-8"
+	if format == "rss" {
+		body, err = feed.RSS(f)
+		contentType = "application/rss+xml; charset=utf-8"
+	} else {
+		body, err = feed.Atom(f)
+	}
+	if err != nil {
+		log.Printf("生成订阅源失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成失败"})
+		return
+	}
+
+	c.Data(http.StatusOK, contentType, body)
+}
+
+// feedNotModified set ETag and Last-Modified, true when the client copy is
+// still fresh and 304 has been sent. they change with any new notice or fetched detail
+func feedNotModified(c *gin.Context, user *model.User, items []service.NoticeItem, variant string) (time.Time, bool) {
+	lastModified := user.CreatedAt
+	h := sha256.New()
+	fmt.Fprint(h, variant)
+	for _, it := range items {
+		updated := it.ReceivedAt
+		if it.UpdatedAt.After(updated) {
+			updated = it.UpdatedAt
+		}
+		if updated.After(lastModified) {
+			lastModified = updated
+		}
+		fmt.Fprintf(h, "|%d:%d", it.UserNoticeID, updated.UnixNano())
+	}
+	lastModified = lastModified.UTC().Truncate(time.Second)
+	etag := fmt.Sprintf(`"%x"`, h.Sum(nil)[:16])
+
+	c.Header("ETag", etag)
+	c.Header("Last-Modified", lastModified.Format(http.TimeFormat))
+	c.Header("Cache-Control", "private, max-age=300")
+
+	if match := c.GetHeader("If-None-Match"); match != "" {
+		if match == "*" || strings.Contains(match, etag) {
+			c.Status(http.StatusNotModified)
+			return lastModified, true
+		}
+	} else if since, err := http.ParseTime(c.GetHeader("If-Modified-Since")); err == nil && !lastModified.After(since) {
+		c.Status(http.StatusNotModified)
+		return lastModified, true
+	}
+	return lastModified, false
+}
+
+// CalendarHandler GET /calendar/:file, file is <token>.ics. notices with a
+// parsable deadline or date become events, the deadline wins
+func CalendarHandler(c *gin.Context) {
+	file := c.Param("file")
+	if !strings.HasSuffix(file, ".ics") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "不支持的格式"})
+		return
+	}
+
+	user, err := service.UserByFeedToken(strings.TrimSuffix(file, ".ics"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "日历不存在"})
+		return
+	}
+
+	items, err := service.RecentNotices(user.ID, calendarLimit)
+	if err != nil {
+		log.Printf("查询日历失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		return
+	}
+
+	if _, fresh := feedNotModified(c, user, items, "ics"); fresh {
+		return
+	}
+
+	loc := user.Location(service.ServerLocation())
+	var events []feed.Event
+	for _, it := range items {
+		summary := it.Title
+		start, hasTime, ok := common.ParseDate(it.Deadline, loc)
+		if ok {
+			summary = "截止：" + it.Title
+		} else if start, hasTime, ok = common.ParseDate(it.Date, loc); !ok {
+			continue
+		}
+
+		events = append(events, feed.Event{
+			UID:      fmt.Sprintf("%s-%s@noticat", it.Client, it.ContentHash),
+			Summary:  summary,
+			URL:      it.URL,
+			Category: it.Client,
+			Start:    start,
+			AllDay:   !hasTime,
+			Stamp:    it.ReceivedAt,
+		})
+	}
+
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", feed.ICal("NotiCat · "+user.Username, events))
+}
+
+// GetFeedHandler GET /api/feed, the feed urls of the user
+func GetFeedHandler(c *gin.Context) {
+	userIDVal, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+	var userID uint
+	if val, ok := userIDVal.(float64); ok {
+		userID = uint(val)
+	} else if val, ok := userIDVal.(uint); ok {
+		userID = val
+	} else {
+		log.Printf("实际类型是: %T", userIDVal)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "身份类型错误"})
+		return
+	}
+
+	token, err := service.FeedToken(userID)
+	if err != nil {
+		log.Printf("获取订阅源令牌失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "系统繁忙，请稍后再试"})
+		return
+	}
+
+	c.JSON(http.StatusOK, feedURLs(token))
+}
+
+// RotateFeedHandler POST /api/feed/rotate, the old urls stop working
+func RotateFeedHandler(c *gin.Context) {
+	userIDVal, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+	var userID uint
+	if val, ok := userIDVal.(float64); ok {
+		userID = uint(val)
+	} else if val, ok := userIDVal.(uint); ok {
+		userID = val
+	} else {
+		log.Printf("实际类型是: %T", userIDVal)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "身份类型错误"})
+		return
+	}
+
+	token, err := service.RotateFeedToken(userID)
+	if err != nil {
+		log.Printf("重置订阅源令牌失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "系统繁忙，请稍后再试"})
+		return
+	}
+
+	c.JSON(http.StatusOK, feedURLs(token))
+}
@@ -0,0 +1,380 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"noticat/internal/service"
+)
+
+type noticeResponse struct {
+	ID             uint       `json:"id"`
+	SubscriptionID uint       `json:"subscription_id"`
+	Client         string     `json:"client"`
+	Title          string     `json:"title"`
+	URL            string     `json:"url"`
+	Date           string     `json:"date"`
+	Deadline       string     `json:"deadline,omitempty"`
+	PublishedAt    *time.Time `json:"published_at"`
+	Body           string     `json:"body,omitempty"`
+	ReceivedAt     time.Time  `json:"received_at"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	Read           bool       `json:"read"`
+	ReadAt         *time.Time `json:"read_at,omitempty"`
+	Starred        bool       `json:"starred"`
+	Archived       bool       `json:"archived"`
+	ArchivedAt     *time.Time `json:"archived_at,omitempty"`
+}
+
+func toNoticeResponse(it *service.NoticeItem) noticeResponse {
+	return noticeResponse{
+		ID:             it.UserNoticeID,
+		SubscriptionID: it.SubscriptionID,
+		Client:         it.Client,
+		Title:          it.Title,
+		URL:            it.URL,
+		Date:           it.Date,
+		Deadline:       it.Deadline,
+		PublishedAt:    it.PublishedAt,
+		Body:           it.Body,
+		ReceivedAt:     it.ReceivedAt,
+		DeliveredAt:    it.DeliveredAt,
+		Read:           it.ReadAt != nil,
+		ReadAt:         it.ReadAt,
+		Starred:        it.Starred,
+		Archived:       it.ArchivedAt != nil,
+		ArchivedAt:     it.ArchivedAt,
+	}
+}
+
+// GetNoticesHandler GET /api/notices?subscription_id=&client=&unread=&starred=&archived=&sort=&page=&page_size=
+func GetNoticesHandler(c *gin.Context) {
+	userIDVal, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+	var userID uint
+	if val, ok := userIDVal.(float64); ok {
+		userID = uint(val)
+	} else if val, ok := userIDVal.(uint); ok {
+		userID = val
+	} else {
+		log.Printf("实际类型是: %T", userIDVal)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "身份类型错误"})
+		return
+	}
+
+	q := service.InboxQuery{
+		Client:   c.Query("client"),
+		Unread:   c.Query("unread") == "true",
+		Starred:  c.Query("starred") == "true",
+		Archived: c.DefaultQuery("archived", service.ArchivedExclude),
+		Sort:     c.Query("sort"),
+	}
+	if subID := c.Query("subscription_id"); subID != "" {
+		id, err := strconv.ParseUint(subID, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "format error"})
+			return
+		}
+		q.SubscriptionID = uint(id)
+	}
+	switch q.Archived {
+	case service.ArchivedExclude, service.ArchivedOnly, service.ArchivedAll:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "archived 只能是 true、false 或 all"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	q.Page = max(page, 1)
+	q.PageSize = min(max(pageSize, 1), 100)
+
+	notices, total, err := service.ListInbox(userID, &q)
+	if err != nil {
+		log.Printf("查询通知失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		return
+	}
+
+	items := make([]noticeResponse, 0, len(notices))
+	for i := range notices {
+		items = append(items, toNoticeResponse(&notices[i]))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total":     total,
+		"page":      q.Page,
+		"page_size": q.PageSize,
+		"items":     items,
+	})
+}
+
+// GetNoticeHandler GET /api/notice/:id, with the fetched detail
+func GetNoticeHandler(c *gin.Context) {
+	userIDVal, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+	var userID uint
+	if val, ok := userIDVal.(float64); ok {
+		userID = uint(val)
+	} else if val, ok := userIDVal.(uint); ok {
+		userID = val
+	} else {
+		log.Printf("实际类型是: %T", userIDVal)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "身份类型错误"})
+		return
+	}
+
+	noticeID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format error"})
+		return
+	}
+
+	item, err := service.GetInboxNotice(userID, uint(noticeID))
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, toNoticeResponse(item))
+	case errors.Is(err, service.ErrNoticeNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		log.Printf("查询通知失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+	}
+}
+
+// UpdateNoticeHandler PATCH /api/notice/:id {"read", "starred", "archived"}, omitted: unchanged
+func UpdateNoticeHandler(c *gin.Context) {
+	var input struct {
+		Read     *bool `json:"read"`
+		Starred  *bool `json:"starred"`
+		Archived *bool `json:"archived"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format error"})
+		return
+	}
+
+	userIDVal, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+	var userID uint
+	if val, ok := userIDVal.(float64); ok {
+		userID = uint(val)
+	} else if val, ok := userIDVal.(uint); ok {
+		userID = val
+	} else {
+		log.Printf("实际类型是: %T", userIDVal)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "身份类型错误"})
+		return
+	}
+
+	noticeID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format error"})
+		return
+	}
+
+	state := service.NoticeState{Read: input.Read, Starred: input.Starred, Archived: input.Archived}
+	if _, err := service.UpdateNoticeState(userID, []uint{uint(noticeID)}, &state); err != nil {
+		log.Printf("更新通知状态失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "系统繁忙，请稍后再试"})
+		return
+	}
+
+	item, err := service.GetInboxNotice(userID, uint(noticeID))
+	if err != nil {
+		if errors.Is(err, service.ErrNoticeNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("查询通知失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		return
+	}
+
+	item.Body = ""
+	c.JSON(http.StatusOK, toNoticeResponse(item))
+}
+
+// UpdateNoticesHandler PATCH /api/notices {"ids", "read", "starred", "archived"}
+func UpdateNoticesHandler(c *gin.Context) {
+	var input struct {
+		IDs      []uint `json:"ids" binding:"required,min=1,max=500"`
+		Read     *bool  `json:"read"`
+		Starred  *bool  `json:"starred"`
+		Archived *bool  `json:"archived"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format error"})
+		return
+	}
+
+	userIDVal, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+	var userID uint
+	if val, ok := userIDVal.(float64); ok {
+		userID = uint(val)
+	} else if val, ok := userIDVal.(uint); ok {
+		userID = val
+	} else {
+		log.Printf("实际类型是: %T", userIDVal)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "身份类型错误"})
+		return
+	}
+
+	state := service.NoticeState{Read: input.Read, Starred: input.Starred, Archived: input.Archived}
+	updated, err := service.UpdateNoticeState(userID, input.IDs, &state)
+	if err != nil {
+		log.Printf("更新通知状态失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "系统繁忙，请稍后再试"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "更新成功", "updated": updated})
+}
+
+// ReadAllNoticesHandler POST /api/notices/read-all?subscription_id=
+func ReadAllNoticesHandler(c *gin.Context) {
+	userIDVal, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+	var userID uint
+	if val, ok := userIDVal.(float64); ok {
+		userID = uint(val)
+	} else if val, ok := userIDVal.(uint); ok {
+		userID = val
+	} else {
+		log.Printf("实际类型是: %T", userIDVal)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "身份类型错误"})
+		return
+	}
+
+	var subID uint64
+	if s := c.Query("subscription_id"); s != "" {
+		var err error
+		if subID, err = strconv.ParseUint(s, 10, 64); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "format error"})
+			return
+		}
+	}
+
+	updated, err := service.MarkAllRead(userID, uint(subID))
+	if err != nil {
+		log.Printf("标记全部已读失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "系统繁忙，请稍后再试"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已全部标记为已读", "updated": updated})
+}
+
+type searchResponse struct {
+	noticeResponse
+	Highlight string `json:"highlight"`
+	Snippet   string `json:"snippet"`
+}
+
+// SearchNoticesHandler GET /api/notices/search?q=&client=&from=&to=&page=&page_size=
+func SearchNoticesHandler(c *gin.Context) {
+	userIDVal, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+	var userID uint
+	if val, ok := userIDVal.(float64); ok {
+		userID = uint(val)
+	} else if val, ok := userIDVal.(uint); ok {
+		userID = val
+	} else {
+		log.Printf("实际类型是: %T", userIDVal)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "身份类型错误"})
+		return
+	}
+
+	q := service.SearchQuery{
+		Query:  strings.TrimSpace(c.Query("q")),
+		Client: c.Query("client"),
+	}
+	if q.Query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "搜索内容不能为空"})
+		return
+	}
+	if from := c.Query("from"); from != "" {
+		t, err := parseDateParam(from, false)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from 格式错误"})
+			return
+		}
+		q.From = t
+	}
+	if to := c.Query("to"); to != "" {
+		t, err := parseDateParam(to, true)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to 格式错误"})
+			return
+		}
+		q.To = t
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	q.Page = max(page, 1)
+	q.PageSize = min(max(pageSize, 1), 100)
+
+	results, total, err := service.SearchNotices(userID, &q)
+	if err != nil {
+		log.Printf("搜索通知失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		return
+	}
+
+	items := make([]searchResponse, 0, len(results))
+	for i := range results {
+		items = append(items, searchResponse{
+			noticeResponse: toNoticeResponse(&results[i].NoticeItem),
+			Highlight:      results[i].Highlight,
+			Snippet:        results[i].Snippet,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total":     total,
+		"page":      q.Page,
+		"page_size": q.PageSize,
+		"items":     items,
+	})
+}
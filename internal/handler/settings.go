@@ -0,0 +1,170 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"noticat/internal/model"
+	"noticat/internal/service"
+	"noticat/pkg/global"
+)
+
+func GetSettingsHandler(c *gin.Context) {
+	userIDVal, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+	var userID uint
+	if val, ok := userIDVal.(float64); ok {
+		userID = uint(val)
+	} else if val, ok := userIDVal.(uint); ok {
+		userID = val
+	} else {
+		log.Printf("实际类型是: %T", userIDVal)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "身份类型错误"})
+		return
+	}
+
+	var user model.User
+	if err := global.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"delivery_mode":       user.DeliveryMode,
+		"digest_time":         user.DigestTime,
+		"digest_weekday":      user.DigestWeekday,
+		"timezone":            user.Timezone,
+		"quiet_start":         user.QuietStart,
+		"quiet_end":           user.QuietEnd,
+		"weekend_quiet_start": user.WeekendQuietStart,
+		"weekend_quiet_end":   user.WeekendQuietEnd,
+		"attach_max_file_mb":  user.AttachMaxFileMB,
+		"attach_max_total_mb": user.AttachMaxTotalMB,
+		"attach_zip":          user.AttachZip,
+	})
+}
+
+func UpdateSettingsHandler(c *gin.Context) {
+	var input struct {
+		DeliveryMode  string `json:"delivery_mode" binding:"required,oneof=immediate hourly daily weekly"`
+		DigestTime    string `json:"digest_time"`
+		DigestWeekday int    `json:"digest_weekday" binding:"min=0,max=6"`
+
+		Timezone          string `json:"timezone"`
+		QuietStart        string `json:"quiet_start"`
+		QuietEnd          string `json:"quiet_end"`
+		WeekendQuietStart string `json:"weekend_quiet_start"`
+		WeekendQuietEnd   string `json:"weekend_quiet_end"`
+
+		// 0 / empty: the server default, at most the server limit
+		AttachMaxFileMB  int    `json:"attach_max_file_mb" binding:"min=0"`
+		AttachMaxTotalMB int    `json:"attach_max_total_mb" binding:"min=0"`
+		AttachZip        string `json:"attach_zip" binding:"omitempty,oneof=off auto always"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format error"})
+		return
+	}
+	if input.DigestTime == "" {
+		input.DigestTime = "08:00"
+	}
+
+	// every time must be HH:MM, quiet hours may be empty
+	times := map[string]string{
+		"digest_time":         input.DigestTime,
+		"quiet_start":         input.QuietStart,
+		"quiet_end":           input.QuietEnd,
+		"weekend_quiet_start": input.WeekendQuietStart,
+		"weekend_quiet_end":   input.WeekendQuietEnd,
+	}
+	for key, value := range times {
+		if value == "" {
+			continue
+		}
+		if _, err := time.Parse("15:04", value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": key + " 格式应为 HH:MM"})
+			return
+		}
+	}
+	if (input.QuietStart == "") != (input.QuietEnd == "") ||
+		(input.WeekendQuietStart == "") != (input.WeekendQuietEnd == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "免打扰时段需要同时设置开始和结束时间"})
+		return
+	}
+
+	maxFileMB, maxTotalMB := service.AttachmentLimitsMB()
+	if input.AttachMaxFileMB > maxFileMB || input.AttachMaxTotalMB > maxTotalMB {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("附件大小不能超过服务器限制（单个 %d MB，合计 %d MB）", maxFileMB, maxTotalMB),
+		})
+		return
+	}
+
+	if input.Timezone != "" {
+		if _, err := time.LoadLocation(input.Timezone); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的时区: " + input.Timezone})
+			return
+		}
+	}
+
+	userIDVal, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+	var userID uint
+	if val, ok := userIDVal.(float64); ok {
+		userID = uint(val)
+	} else if val, ok := userIDVal.(uint); ok {
+		userID = val
+	} else {
+		log.Printf("实际类型是: %T", userIDVal)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "身份类型错误"})
+		return
+	}
+
+	err := global.DB.Model(&model.User{}).Where("id = ?", userID).Updates(map[string]any{
+		"delivery_mode":  input.DeliveryMode,
+		"digest_time":    input.DigestTime,
+		"digest_weekday": input.DigestWeekday,
+
+		"timezone":            input.Timezone,
+		"quiet_start":         input.QuietStart,
+		"quiet_end":           input.QuietEnd,
+		"weekend_quiet_start": input.WeekendQuietStart,
+		"weekend_quiet_end":   input.WeekendQuietEnd,
+
+		"attach_max_file_mb":  input.AttachMaxFileMB,
+		"attach_max_total_mb": input.AttachMaxTotalMB,
+		"attach_zip":          input.AttachZip,
+	}).Error
+	if err != nil {
+		log.Printf("更新用户设置失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "系统繁忙，请稍后再试"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已保存"})
+}
@@ -0,0 +1,87 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+
+	"noticat/internal/stream"
+)
+
+// StreamHandler push new notices with server-sent events, resume with Last-Event-ID
+func StreamHandler(c *gin.Context) {
+	userIDVal, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+	var userID uint
+	if val, ok := userIDVal.(float64); ok {
+		userID = uint(val)
+	} else if val, ok := userIDVal.(uint); ok {
+		userID = val
+	} else {
+		log.Printf("实际类型是: %T", userIDVal)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "身份类型错误"})
+		return
+	}
+
+	// some clients can not set headers on reconnect, accept a query too
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+	lastID, _ := strconv.ParseUint(lastEventID, 10, 64)
+
+	replay, events, cancel := stream.Subscribe(userID, lastID)
+	defer cancel()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// nginx buffers responses by default
+	c.Header("X-Accel-Buffering", "no")
+
+	c.Render(-1, sse.Event{Retry: 5000, Event: "ready", Data: gin.H{"replayed": len(replay)}})
+	for _, ev := range replay {
+		c.Render(-1, sse.Event{Id: strconv.FormatUint(ev.ID, 10), Event: ev.Type, Data: ev})
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(25 * time.Second)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				return false
+			}
+			c.Render(-1, sse.Event{Id: strconv.FormatUint(ev.ID, 10), Event: ev.Type, Data: ev})
+			return true
+		case <-heartbeat.C:
+			c.Render(-1, sse.Event{Event: "ping", Data: time.Now().Unix()})
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
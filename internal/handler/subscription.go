@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -39,7 +40,7 @@ import (
 
 func CreateSubscriptionHandler(c *gin.Context) {
 	type FilterInput struct {
-		Type       string `json:"type" binding:"required,oneof=regex keyword"`
+		Type       string `json:"type" binding:"required,oneof=regex keyword expr"`
 		Pattern    string `json:"pattern" binding:"required"`
 		IgnoreCase bool   `json:"ignore_case"`
 	}
@@ -50,6 +51,14 @@ func CreateSubscriptionHandler(c *gin.Context) {
 		Credentials    map[string]any `json:"credentials"`
 		Extra          map[string]any `json:"extra"`
 		Filters        []FilterInput  `json:"filters" binding:"omitempty,dive"`
+		// drop notices older than this many days, 0: keep all, nil: unchanged
+		MaxAgeDays *int `json:"max_age_days" binding:"omitempty,min=0,max=3650"`
+		// send some of the listed notices right away, only for a new subscription
+		Backfill *struct {
+			Mode  string `json:"mode" binding:"omitempty,oneof=none latest since"`
+			Count int    `json:"count"`
+			Since string `json:"since"`
+		} `json:"backfill"`
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -59,6 +68,16 @@ func CreateSubscriptionHandler(c *gin.Context) {
 
 	// fmt.Printf("%#v\n", input.Filters)
 
+	backfill := &service.Backfill{Mode: service.BackfillNone}
+	if input.Backfill != nil {
+		var err error
+		backfill, err = service.ParseBackfill(input.Backfill.Mode, input.Backfill.Count, input.Backfill.Since)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("回填参数错误: latest 需要 1-%d 的 count，since 需要合法日期", service.BackfillLimit)})
+			return
+		}
+	}
+
 	// check client
 	rawClient := strings.ToLower(input.Client)
 	clientType := bridge.Client(rawClient)
@@ -110,12 +129,23 @@ func CreateSubscriptionHandler(c *gin.Context) {
 	// try to compile filter
 	if len(input.Filters) > 0 {
 		for _, f := range input.Filters {
-			if _, regErr := common.NewFilter(f.Pattern, f.Type == "regex", f.IgnoreCase); regErr != nil {
+			_, compileErr := common.CompileExpr(filterExpression(f.Type, f.Pattern, f.IgnoreCase), service.ServerLocation())
+			if compileErr == nil {
+				continue
+			}
+			var exprErr *common.ExprError
+			if f.Type == "expr" && errors.As(compileErr, &exprErr) {
 				c.JSON(http.StatusBadRequest, gin.H{
-					"error": fmt.Sprintf("过滤器正则表达式格式错误: %s", f.Pattern),
+					"error":    fmt.Sprintf("过滤表达式格式错误: %s", exprErr.Error()),
+					"pattern":  f.Pattern,
+					"position": exprErr.Pos,
 				})
 				return
 			}
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("过滤器正则表达式格式错误: %s", f.Pattern),
+			})
+			return
 		}
 	}
 
@@ -135,6 +165,11 @@ func CreateSubscriptionHandler(c *gin.Context) {
 		if err != nil {
 			return err
 		}
+		if input.MaxAgeDays != nil {
+			if err := tx.Model(&sub).Update("max_age_days", *input.MaxAgeDays).Error; err != nil {
+				return err
+			}
+		}
 		// filter
 		if len(input.Filters) > 0 {
 
@@ -155,6 +190,7 @@ func CreateSubscriptionHandler(c *gin.Context) {
 						Type:           f.Type,
 						Pattern:        f.Pattern,
 						IgnoreCase:     f.IgnoreCase,
+						Expression:     filterExpression(f.Type, f.Pattern, f.IgnoreCase),
 					})
 				}
 				if len(newFilters) > 0 {
@@ -174,6 +210,7 @@ func CreateSubscriptionHandler(c *gin.Context) {
 				err := tx.Where(&newFilter).Assign(map[string]any{
 					"type":        f.Type,
 					"ignore_case": f.IgnoreCase,
+					"expression":  filterExpression(f.Type, f.Pattern, f.IgnoreCase),
 				}).FirstOrCreate(&newFilter).Error
 				if err != nil {
 					log.Printf("保存过滤规则失败：%v", err)
@@ -196,7 +233,7 @@ func CreateSubscriptionHandler(c *gin.Context) {
 	}
 
 	// try to fetch
-	_, notices, err := service.FetchByConfig(rawClient, credsStr, extraStr)
+	fetchCtx, notices, err := service.FetchByConfig(rawClient, credsStr, extraStr)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "无法连接源站，请检查凭证或配置"})
 		return
@@ -207,6 +244,13 @@ func CreateSubscriptionHandler(c *gin.Context) {
 		return
 	}
 
+	// left unseen, DispatchBackfill sends them once the subscription is saved
+	backfilled := backfill.Select(notices)
+	skip := make(map[string]bool, len(backfilled))
+	for _, notice := range backfilled {
+		skip[notice.ContentHash()] = true
+	}
+
 	var finalTaskID uint
 	var finalSubscriptionID uint
 	err = global.DB.Transaction(func(tx *gorm.DB) error {
@@ -229,14 +273,17 @@ func CreateSubscriptionHandler(c *gin.Context) {
 		// task_id
 		finalTaskID = task.ID
 
-		// find or create UserSubscription (soft delete->restore with Assign)
+		// find or create UserSubscription (soft delete->restore with Assign),
+		// the settings of the request go in with it
+		assign := map[string]any{"deleted_at": nil}
+		if input.MaxAgeDays != nil {
+			assign["max_age_days"] = *input.MaxAgeDays
+		}
 		var sub model.UserSubscription
 		err = tx.Unscoped().Where(&model.UserSubscription{
 			UserID: userID,
 			TaskID: task.ID,
-		}).Assign(map[string]any{
-			"deleted_at": nil,
-		}).FirstOrCreate(&sub).Error
+		}).Assign(assign).FirstOrCreate(&sub).Error
 		if err != nil {
 			log.Printf("订阅失败：%v", err)
 			return err
@@ -252,6 +299,7 @@ func CreateSubscriptionHandler(c *gin.Context) {
 					Type:           f.Type,
 					Pattern:        f.Pattern,
 					IgnoreCase:     f.IgnoreCase,
+					Expression:     filterExpression(f.Type, f.Pattern, f.IgnoreCase),
 				}
 				err := tx.Where(&newFilter).FirstOrCreate(&newFilter).Error
 				if err != nil {
@@ -264,6 +312,11 @@ func CreateSubscriptionHandler(c *gin.Context) {
 		// init dispatch
 		success := 0
 		for _, notice := range notices {
+			// the backfill delivers it, it is marked seen then
+			if skip[notice.ContentHash()] {
+				success += 1
+				continue
+			}
 			un := model.UserNotice{
 				UserID:      sub.UserID,
 				Client:      rawClient,
@@ -292,7 +345,23 @@ func CreateSubscriptionHandler(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "订阅成功", "task_id": finalTaskID, "subscription_id": finalSubscriptionID})
+	go service.DispatchBackfill(finalSubscriptionID, fetchCtx, backfilled)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":         "订阅成功",
+		"task_id":         finalTaskID,
+		"subscription_id": finalSubscriptionID,
+		"backfill":        len(backfilled),
+	})
+}
+
+// filterExpression the expression a filter is matched with, keyword and regex
+// filters are converted
+func filterExpression(filterType string, pattern string, ignoreCase bool) string {
+	if filterType == "expr" {
+		return pattern
+	}
+	return common.LegacyFilterExpr(pattern, filterType == "regex", ignoreCase)
 }
 
 func DeleteSubscriptionHandler(c *gin.Context) {
@@ -313,46 +382,17 @@ func DeleteSubscriptionHandler(c *gin.Context) {
 		return
 	}
 
-	subscriptionID := c.Param("id")
-
-	err := global.DB.Transaction(func(tx *gorm.DB) error {
-		var sub model.UserSubscription
-		if err := tx.Where("id = ? AND user_id = ?", subscriptionID, userID).First(&sub).Error; err != nil {
-			log.Printf("错误: %v", err)
-			return fmt.Errorf("订阅不存在")
-		}
-		taskID := sub.TaskID
-
-		// delete filters
-		if err := tx.Unscoped().Where("subscription_id = ?", sub.ID).Delete(&model.SubscriptionFilter{}).Error; err != nil {
-			log.Printf("错误: %v", err)
-			return fmt.Errorf("无法删除filters")
-		}
-
-		// delete subscription
-		if err := tx.Delete(&sub).Error; err != nil {
-			log.Printf("错误: %v", err)
-			return fmt.Errorf("无法删除订阅")
-		}
-
-		var count int64
-		if err := tx.Model(&model.UserSubscription{}).Where("task_id = ?", taskID).Count(&count).Error; err != nil {
-			log.Printf("错误: %v", err)
-			return fmt.Errorf("无法统计任务订阅数")
-		}
+	subscriptionID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format error"})
+		return
+	}
 
-		if count == 0 {
-			// delete task
-			if err := tx.Delete(&model.FetchTask{}, taskID).Error; err != nil {
-				log.Printf("错误: %v", err)
-				return fmt.Errorf("无法删除任务")
-			}
-			log.Printf("任务 %d 已无订阅者，已彻底移除", taskID)
+	if err := service.DeleteSubscription(userID, uint(subscriptionID)); err != nil {
+		if errors.Is(err, service.ErrSubscriptionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "订阅不存在"})
+			return
 		}
-
-		return nil
-	})
-	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "删除执行失败，数据已安全回滚",
 		})
@@ -458,11 +498,272 @@ func GetSubDetailHandler(c *gin.Context) {
 		return
 	}
 
+	addressIDs, err := service.SubscriptionAddressIDs(sub.ID)
+	if err != nil {
+		log.Printf("查询订阅地址失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"id":          sub.ID,
-		"client":      sub.Task.Client,
-		"extra":       extra,
-		"credentials": credentials,
-		"filters":     sub.Filters,
+		"id":             sub.ID,
+		"client":         sub.Task.Client,
+		"extra":          extra,
+		"credentials":    credentials,
+		"filters":        sub.Filters,
+		"paused":         sub.Paused,
+		"notify_updates": sub.NotifyUpdates,
+		"max_age_days":   sub.MaxAgeDays,
+		"address_ids":    addressIDs,
+		"delivery": gin.H{
+			"mode":           sub.DeliveryMode,
+			"digest_time":    sub.DigestTime,
+			"digest_weekday": sub.DigestWeekday,
+			"urgent":         sub.Urgent,
+		},
 	})
 }
+
+func UpdateSubDeliveryHandler(c *gin.Context) {
+	var input struct {
+		// empty: follow the user settings
+		Mode          string `json:"mode" binding:"omitempty,oneof=immediate hourly daily weekly"`
+		DigestTime    string `json:"digest_time"`
+		DigestWeekday int    `json:"digest_weekday" binding:"min=0,max=6"`
+		// urgent notices ignore quiet hours
+		Urgent bool `json:"urgent"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format error"})
+		return
+	}
+	if input.DigestTime != "" {
+		if _, err := time.Parse("15:04", input.DigestTime); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "digest_time 格式应为 HH:MM"})
+			return
+		}
+	}
+
+	userIDVal, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+	var userID uint
+	if val, ok := userIDVal.(float64); ok {
+		userID = uint(val)
+	} else if val, ok := userIDVal.(uint); ok {
+		userID = val
+	} else {
+		log.Printf("实际类型是: %T", userIDVal)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "身份类型错误"})
+		return
+	}
+
+	result := global.DB.Model(&model.UserSubscription{}).
+		Where("id = ? AND user_id = ?", c.Param("id"), userID).
+		Updates(map[string]any{
+			"delivery_mode":  input.Mode,
+			"digest_time":    input.DigestTime,
+			"digest_weekday": input.DigestWeekday,
+			"urgent":         input.Urgent,
+		})
+	if result.Error != nil {
+		log.Printf("更新订阅投递方式失败: %v", result.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "系统繁忙，请稍后再试"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "订阅不存在"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已保存"})
+}
+
+// PauseSubscriptionHandler PUT /api/subscription/:id/pause {"paused": true}
+func PauseSubscriptionHandler(c *gin.Context) {
+	var input struct {
+		Paused bool `json:"paused"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format error"})
+		return
+	}
+
+	userIDVal, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+	var userID uint
+	if val, ok := userIDVal.(float64); ok {
+		userID = uint(val)
+	} else if val, ok := userIDVal.(uint); ok {
+		userID = val
+	} else {
+		log.Printf("实际类型是: %T", userIDVal)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "身份类型错误"})
+		return
+	}
+
+	subscriptionID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format error"})
+		return
+	}
+
+	if err := service.SetSubscriptionPaused(userID, uint(subscriptionID), input.Paused); err != nil {
+		if errors.Is(err, service.ErrSubscriptionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "订阅不存在"})
+			return
+		}
+		log.Printf("更新订阅暂停状态失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "系统繁忙，请稍后再试"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已保存"})
+}
+
+// UpdateSubUpdatesHandler PUT /api/subscription/:id/updates {"notify_updates": false}
+func UpdateSubUpdatesHandler(c *gin.Context) {
+	var input struct {
+		NotifyUpdates bool `json:"notify_updates"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format error"})
+		return
+	}
+
+	userIDVal, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+	var userID uint
+	if val, ok := userIDVal.(float64); ok {
+		userID = uint(val)
+	} else if val, ok := userIDVal.(uint); ok {
+		userID = val
+	} else {
+		log.Printf("实际类型是: %T", userIDVal)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "身份类型错误"})
+		return
+	}
+
+	subscriptionID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format error"})
+		return
+	}
+
+	if err := service.SetSubscriptionNotifyUpdates(userID, uint(subscriptionID), input.NotifyUpdates); err != nil {
+		if errors.Is(err, service.ErrSubscriptionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "订阅不存在"})
+			return
+		}
+		log.Printf("更新订阅更新提醒失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "系统繁忙，请稍后再试"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已保存"})
+}
+
+// UpdateSubMaxAgeHandler PUT /api/subscription/:id/max-age {"max_age_days": 30}, 0: keep all
+func UpdateSubMaxAgeHandler(c *gin.Context) {
+	var input struct {
+		MaxAgeDays *int `json:"max_age_days" binding:"required,min=0,max=3650"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format error"})
+		return
+	}
+
+	userIDVal, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+	var userID uint
+	if val, ok := userIDVal.(float64); ok {
+		userID = uint(val)
+	} else if val, ok := userIDVal.(uint); ok {
+		userID = val
+	} else {
+		log.Printf("实际类型是: %T", userIDVal)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "身份类型错误"})
+		return
+	}
+
+	subscriptionID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format error"})
+		return
+	}
+
+	if err := service.SetSubscriptionMaxAge(userID, uint(subscriptionID), *input.MaxAgeDays); err != nil {
+		if errors.Is(err, service.ErrSubscriptionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "订阅不存在"})
+			return
+		}
+		log.Printf("更新订阅 max_age_days 失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "系统繁忙，请稍后再试"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已保存"})
+}
+
+// MuteFilterHandler PUT /api/subscription/:id/filter/:filter_id/mute {"muted": true}
+func MuteFilterHandler(c *gin.Context) {
+	var input struct {
+		Muted bool `json:"muted"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format error"})
+		return
+	}
+
+	userIDVal, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+	var userID uint
+	if val, ok := userIDVal.(float64); ok {
+		userID = uint(val)
+	} else if val, ok := userIDVal.(uint); ok {
+		userID = val
+	} else {
+		log.Printf("实际类型是: %T", userIDVal)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "身份类型错误"})
+		return
+	}
+
+	subscriptionID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format error"})
+		return
+	}
+	filterID, err := strconv.ParseUint(c.Param("filter_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format error"})
+		return
+	}
+
+	err = service.SetFilterMuted(userID, uint(subscriptionID), uint(filterID), input.Muted)
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, gin.H{"message": "已保存"})
+	case errors.Is(err, service.ErrSubscriptionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "订阅不存在"})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "过滤器不存在"})
+	default:
+		log.Printf("更新过滤器静音状态失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "系统繁忙，请稍后再试"})
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"noticat/internal/model"
+	"noticat/internal/notifier"
+	"noticat/pkg/global"
+)
+
+func CreateTargetHandler(c *gin.Context) {
+	var input struct {
+		SubscriptionID uint   `json:"subscription_id"`
+		Channel        string `json:"channel" binding:"required"`
+		Address        string `json:"address" binding:"required"`
+		Secret         string `json:"secret"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format error"})
+		return
+	}
+
+	userIDVal, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+	var userID uint
+	if val, ok := userIDVal.(float64); ok {
+		userID = uint(val)
+	} else if val, ok := userIDVal.(uint); ok {
+		userID = val
+	} else {
+		log.Printf("实际类型是: %T", userIDVal)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "身份类型错误"})
+		return
+	}
+
+	// the account email is always delivered, not a target
+	if input.Channel == notifier.ChannelEmail {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的通知渠道"})
+		return
+	}
+
+	// subscription_id == 0: for every subscription
+	if input.SubscriptionID != 0 {
+		var sub model.UserSubscription
+		if err := global.DB.Where("id = ? AND user_id = ?", input.SubscriptionID, userID).First(&sub).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "订阅不存在"})
+			return
+		}
+	}
+
+	// webhook without secret can not be verified by the receiver
+	if input.Secret == "" && input.Channel == notifier.ChannelWebhook {
+		b := make([]byte, 24)
+		rand.Read(b)
+		input.Secret = hex.EncodeToString(b)
+	}
+
+	if err := notifier.Validate(&notifier.Target{
+		Channel: input.Channel,
+		Address: input.Address,
+		Secret:  input.Secret,
+	}); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	target := model.NotifyTarget{
+		UserID:         userID,
+		SubscriptionID: input.SubscriptionID,
+		Channel:        input.Channel,
+		Address:        input.Address,
+		Secret:         input.Secret,
+	}
+	if err := global.DB.Create(&target).Error; err != nil {
+		log.Printf("保存通知渠道失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "系统繁忙，请稍后再试"})
+		return
+	}
+
+	// the secret is only shown once
+	c.JSON(http.StatusOK, gin.H{"message": "添加成功", "id": target.ID, "secret": target.Secret})
+}
+
+func GetTargetsHandler(c *gin.Context) {
+	userIDVal, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+	var userID uint
+	if val, ok := userIDVal.(float64); ok {
+		userID = uint(val)
+	} else if val, ok := userIDVal.(uint); ok {
+		userID = val
+	} else {
+		log.Printf("实际类型是: %T", userIDVal)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "身份类型错误"})
+		return
+	}
+
+	query := global.DB.Where("user_id = ?", userID)
+	if subID := c.Query("subscription_id"); subID != "" {
+		query = query.Where("subscription_id = ?", subID)
+	}
+
+	var targets []model.NotifyTarget
+	if err := query.Order("id").Find(&targets).Error; err != nil {
+		log.Printf("查询通知渠道失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, targets)
+}
+
+func DeleteTargetHandler(c *gin.Context) {
+	userIDVal, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+	var userID uint
+	if val, ok := userIDVal.(float64); ok {
+		userID = uint(val)
+	} else if val, ok := userIDVal.(uint); ok {
+		userID = val
+	} else {
+		log.Printf("实际类型是: %T", userIDVal)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "身份类型错误"})
+		return
+	}
+
+	result := global.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).Delete(&model.NotifyTarget{})
+	if result.Error != nil {
+		log.Printf("删除通知渠道失败: %v", result.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除失败"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "通知渠道不存在"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "删除成功"})
+}
@@ -23,8 +23,8 @@ import (
 
 	"github.com/gin-gonic/gin"
 
-	"noticat/internal/bridge"
 	"noticat/internal/model"
+	"noticat/internal/notifier"
 	"noticat/internal/service"
 	"noticat/pkg/global"
 )
@@ -84,15 +84,12 @@ func TestFetchHandler(c *gin.Context) {
 		return
 	}
 
-	err = bridge.SendMail(&bridge.SendOptions{
-		SMTPServer:  global.SMTPSERVER,
-		Account:     global.ACCOUNT,
-		AuthCode:    global.AUTHCODE,
-		Subject:     "[Noticat]Test Fetch",
-		Body:        notice.Title,
-		From:        global.ACCOUNT,
-		To:          user.Email,
-		Attachments: []string{},
+	err = notifier.Send(&notifier.Target{
+		Channel: notifier.ChannelEmail,
+		Address: user.Email,
+	}, &notifier.Message{
+		Subject: "[Noticat]Test Fetch",
+		Body:    notice.Title,
 	})
 	if err != nil {
 		c.JSON(http.StatusForbidden, gin.H{"error": "fail to send email to user"})
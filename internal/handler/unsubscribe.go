@@ -0,0 +1,174 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"noticat/internal/model"
+	"noticat/internal/service"
+	"noticat/pkg/global"
+)
+
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <title>NotiCat 退订</title>
+    <style>
+    body { font-family: "Helvetica Neue", Helvetica, Arial, sans-serif; line-height: 1.6; padding: 2rem; background: #f5f5f5; color: #333; }
+    form { display: inline-block; margin: 0.5rem 0.5rem 0 0; }
+    button { padding: 0.5rem 1rem; border: 1px solid #ccc; border-radius: 4px; background: #fff; cursor: pointer; }
+    button.danger { color: #c0392b; border-color: #c0392b; }
+    </style>
+</head>
+<body>
+{{if .Message}}
+    <p>{{.Message}}</p>
+{{else if .All}}
+    <h2>管理全部订阅（{{.Count}} 个）</h2>
+    <form method="post"><input type="hidden" name="action" value="pause"><button>暂停全部订阅</button></form>
+{{else}}
+    <h2>管理订阅：{{.Client}}</h2>
+    <form method="post"><input type="hidden" name="action" value="pause"><button>暂停此订阅</button></form>
+    {{if .Filter}}<form method="post"><input type="hidden" name="action" value="mute"><button>不再接收匹配「{{.Filter}}」的通知</button></form>{{end}}
+    <form method="post"><input type="hidden" name="action" value="delete"><button class="danger">删除此订阅</button></form>
+{{end}}
+</body>
+</html>`))
+
+type unsubscribeView struct {
+	// the link of a digest, for every subscription
+	All     bool
+	Count   int64
+	Client  string
+	Filter  string
+	Message string
+}
+
+func renderUnsubscribe(c *gin.Context, status int, view unsubscribeView) {
+	c.Status(status)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	if err := unsubscribePage.Execute(c.Writer, view); err != nil {
+		log.Printf("渲染退订页面失败: %v", err)
+	}
+}
+
+// UnsubscribePageHandler GET /unsubscribe/:token, no login needed
+func UnsubscribePageHandler(c *gin.Context) {
+	claims, err := service.ParseUnsubscribeToken(c.Param("token"))
+	if err != nil {
+		renderUnsubscribe(c, http.StatusBadRequest, unsubscribeView{Message: err.Error()})
+		return
+	}
+
+	if claims.SubscriptionID == 0 {
+		view := unsubscribeView{All: true}
+		if err := global.DB.Model(&model.UserSubscription{}).
+			Where("user_id = ? AND paused = ?", claims.UserID, false).
+			Count(&view.Count).Error; err != nil {
+			log.Printf("查询用户 %d 的订阅失败: %v", claims.UserID, err)
+			renderUnsubscribe(c, http.StatusInternalServerError, unsubscribeView{Message: "系统繁忙，请稍后再试"})
+			return
+		}
+		if view.Count == 0 {
+			view.Message = "全部订阅均已暂停，可在 NotiCat 中恢复"
+		}
+		renderUnsubscribe(c, http.StatusOK, view)
+		return
+	}
+
+	var sub model.UserSubscription
+	if err := global.DB.Preload("Task").
+		Where("id = ? AND user_id = ?", claims.SubscriptionID, claims.UserID).
+		First(&sub).Error; err != nil {
+		renderUnsubscribe(c, http.StatusNotFound, unsubscribeView{Message: "订阅不存在或已删除"})
+		return
+	}
+
+	view := unsubscribeView{Client: sub.Task.Client}
+	if claims.FilterID != 0 {
+		var filter model.SubscriptionFilter
+		if err := global.DB.Where("id = ? AND subscription_id = ?", claims.FilterID, sub.ID).First(&filter).Error; err == nil && !filter.Muted {
+			view.Filter = filter.Pattern
+		}
+	}
+	if sub.Paused {
+		view.Message = "此订阅已暂停，可在 NotiCat 中恢复"
+	}
+
+	renderUnsubscribe(c, http.StatusOK, view)
+}
+
+// UnsubscribeHandler POST /unsubscribe/:token
+// the RFC 8058 one-click POST (List-Unsubscribe=One-Click) pauses the subscription
+func UnsubscribeHandler(c *gin.Context) {
+	claims, err := service.ParseUnsubscribeToken(c.Param("token"))
+	if err != nil {
+		renderUnsubscribe(c, http.StatusBadRequest, unsubscribeView{Message: err.Error()})
+		return
+	}
+
+	action := c.PostForm("action")
+	if c.PostForm("List-Unsubscribe") == "One-Click" {
+		action = "pause"
+	}
+
+	if claims.SubscriptionID == 0 && action != "pause" {
+		renderUnsubscribe(c, http.StatusBadRequest, unsubscribeView{Message: "此链接只能暂停全部订阅"})
+		return
+	}
+
+	var message string
+	switch {
+	case action == "pause" && claims.SubscriptionID == 0:
+		err = service.SetAllSubscriptionsPaused(claims.UserID, true)
+		message = "已暂停全部订阅，可在 NotiCat 中恢复"
+	case action == "pause":
+		err = service.SetSubscriptionPaused(claims.UserID, claims.SubscriptionID, true)
+		message = "已暂停此订阅，可在 NotiCat 中恢复"
+	case action == "mute":
+		if claims.FilterID == 0 {
+			renderUnsubscribe(c, http.StatusBadRequest, unsubscribeView{Message: "此链接没有对应的过滤器"})
+			return
+		}
+		err = service.SetFilterMuted(claims.UserID, claims.SubscriptionID, claims.FilterID, true)
+		message = "已静音此过滤器，可在 NotiCat 中恢复"
+	case action == "delete":
+		err = service.DeleteSubscription(claims.UserID, claims.SubscriptionID)
+		message = "已删除此订阅"
+	default:
+		renderUnsubscribe(c, http.StatusBadRequest, unsubscribeView{Message: "未知操作"})
+		return
+	}
+
+	if err != nil {
+		if errors.Is(err, service.ErrSubscriptionNotFound) {
+			renderUnsubscribe(c, http.StatusNotFound, unsubscribeView{Message: "订阅不存在或已删除"})
+			return
+		}
+		log.Printf("退订操作 %s 失败: %v", action, err)
+		renderUnsubscribe(c, http.StatusInternalServerError, unsubscribeView{Message: "系统繁忙，请稍后再试"})
+		return
+	}
+
+	renderUnsubscribe(c, http.StatusOK, unsubscribeView{Message: message})
+}
@@ -33,8 +33,8 @@ import (
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 
-	"noticat/internal/bridge"
 	"noticat/internal/model"
+	"noticat/internal/notifier"
 	"noticat/pkg/global"
 )
 
@@ -81,15 +81,12 @@ func SendCodeHandler(c *gin.Context) {
 
 	fmt.Printf("目标：%s，验证码：%s\n", input.Email, code)
 
-	err = bridge.SendMail(&bridge.SendOptions{
-		SMTPServer:  global.SMTPSERVER,
-		Account:     global.ACCOUNT,
-		AuthCode:    global.AUTHCODE,
-		Subject:     "[NotiCat]注册验证码：" + code,
-		Body:        "注册验证码" + code,
-		From:        global.ACCOUNT,
-		To:          input.Email,
-		Attachments: []string{},
+	err = notifier.Send(&notifier.Target{
+		Channel: notifier.ChannelEmail,
+		Address: input.Email,
+	}, &notifier.Message{
+		Subject: "[NotiCat]注册验证码：" + code,
+		Body:    "注册验证码" + code,
 	})
 	if err != nil {
 		log.Printf("发送邮件失败: %v", err)
@@ -0,0 +1,83 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mailer
+
+import (
+	"errors"
+	"fmt"
+	"net/textproto"
+)
+
+// use errors.Is(err, mailer.ErrAuth) to know what happened
+var (
+	ErrAuth      = errors.New("smtp authentication failed")
+	ErrRecipient = errors.New("smtp recipient rejected")
+	ErrTransient = errors.New("smtp transient failure")
+	ErrPermanent = errors.New("smtp permanent failure")
+)
+
+// Error an smtp failure, Code is the smtp reply code (0 if no reply)
+type Error struct {
+	Kind  error
+	Stage string
+	Code  int
+	Err   error
+}
+
+func (e *Error) Error() string {
+	if e.Code > 0 {
+		return fmt.Sprintf("%v (%s, code %d): %v", e.Kind, e.Stage, e.Code, e.Err)
+	}
+	return fmt.Sprintf("%v (%s): %v", e.Kind, e.Stage, e.Err)
+}
+
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// IsTransient worth to try again later
+func IsTransient(err error) bool {
+	return errors.Is(err, ErrTransient)
+}
+
+// classify turn an error of one smtp stage into *Error
+func classify(stage string, err error) error {
+	var tpErr *textproto.Error
+	if !errors.As(err, &tpErr) {
+		// net/smtp refuses to auth on plain connection by itself
+		if stage == "auth" {
+			return &Error{Kind: ErrAuth, Stage: stage, Err: err}
+		}
+		// network error, timeout, tls handshake...
+		return &Error{Kind: ErrTransient, Stage: stage, Err: err}
+	}
+
+	e := &Error{Stage: stage, Code: tpErr.Code, Err: err}
+	switch {
+	case stage == "auth" && (tpErr.Code == 535 || tpErr.Code == 534 || tpErr.Code == 530):
+		e.Kind = ErrAuth
+	case tpErr.Code >= 400 && tpErr.Code < 500:
+		e.Kind = ErrTransient
+	case stage == "rcpt":
+		e.Kind = ErrRecipient
+	default:
+		e.Kind = ErrPermanent
+	}
+	return e
+}
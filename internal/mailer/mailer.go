@@ -0,0 +1,228 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package mailer pure go smtp sender, an alternative to mail/bin/send
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	// Server short name (163, qq, gmail...) or url (smtps://host:465)
+	Server   string
+	Account  string
+	AuthCode string
+	Timeout  time.Duration
+}
+
+// rootCAs nil is the system pool, tests trust their own server with it
+var rootCAs *x509.CertPool
+
+type Mail struct {
+	From        string
+	To          string
+	Subject     string
+	Body        string
+	Attachments []string
+	// extra headers (List-Unsubscribe...)
+	Headers map[string]string
+}
+
+// Send deliver one mail, errors are *Error
+func Send(cfg *Config, m *Mail) error {
+	ep, err := resolveServer(cfg.Server)
+	if err != nil {
+		return &Error{Kind: ErrPermanent, Stage: "config", Err: err}
+	}
+
+	payload, err := buildMessage(m)
+	if err != nil {
+		return &Error{Kind: ErrPermanent, Stage: "build", Err: err}
+	}
+
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+
+	tlsConfig := &tls.Config{ServerName: ep.host, RootCAs: rootCAs}
+	var conn net.Conn
+	if ep.mode == tlsImplicit {
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", ep.addr, tlsConfig)
+	} else {
+		conn, err = net.DialTimeout("tcp", ep.addr, timeout)
+	}
+	if err != nil {
+		return classify("dial", err)
+	}
+	// the whole conversation shares one deadline
+	conn.SetDeadline(time.Now().Add(2 * timeout))
+
+	c, err := smtp.NewClient(conn, ep.host)
+	if err != nil {
+		conn.Close()
+		return classify("greeting", err)
+	}
+	defer c.Close()
+
+	if ep.mode != tlsImplicit {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if err := c.StartTLS(tlsConfig); err != nil {
+				return classify("starttls", err)
+			}
+		} else if ep.mode == tlsStartTLS {
+			return &Error{Kind: ErrPermanent, Stage: "starttls", Err: fmt.Errorf("服务器不支持 STARTTLS")}
+		}
+	}
+
+	if cfg.Account != "" {
+		// sending without the configured account would only fail later, or worse, pass
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return &Error{Kind: ErrPermanent, Stage: "auth", Err: fmt.Errorf("服务器不支持 AUTH，无法使用配置的账号登录")}
+		}
+		if err := c.Auth(smtp.PlainAuth("", cfg.Account, cfg.AuthCode, ep.host)); err != nil {
+			return classify("auth", err)
+		}
+	}
+
+	if err := c.Mail(addressOnly(m.From)); err != nil {
+		return classify("mail", err)
+	}
+	if err := c.Rcpt(addressOnly(m.To)); err != nil {
+		return classify("rcpt", err)
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return classify("data", err)
+	}
+	if _, err := w.Write(payload); err != nil {
+		return classify("data", err)
+	}
+	if err := w.Close(); err != nil {
+		return classify("data", err)
+	}
+
+	// the mail is accepted, a failing QUIT does not matter
+	c.Quit()
+	return nil
+}
+
+func buildMessage(m *Mail) ([]byte, error) {
+	boundary := randomHex(16)
+
+	var buf bytes.Buffer
+	writeHeader := func(key, value string) {
+		buf.WriteString(key + ": " + value + "\r\n")
+	}
+
+	writeHeader("From", m.From)
+	writeHeader("To", m.To)
+	writeHeader("Subject", mime.BEncoding.Encode("utf-8", m.Subject))
+	writeHeader("Date", time.Now().Format(time.RFC1123Z))
+	writeHeader("Message-ID", fmt.Sprintf("<%s@%s>", randomHex(12), domainOf(m.From)))
+	keys := make([]string, 0, len(m.Headers))
+	for key := range m.Headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		// no header injection
+		writeHeader(stripCRLF(key), stripCRLF(m.Headers[key]))
+	}
+	writeHeader("MIME-Version", "1.0")
+	writeHeader("Content-Type", fmt.Sprintf("multipart/mixed; boundary=\"%s\"", boundary))
+	buf.WriteString("\r\n")
+
+	// body
+	buf.WriteString("--" + boundary + "\r\n")
+	writeHeader("Content-Type", "text/html; charset=utf-8")
+	writeHeader("Content-Transfer-Encoding", "base64")
+	buf.WriteString("\r\n")
+	writeBase64(&buf, []byte(m.Body))
+
+	for _, path := range m.Attachments {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("读取附件失败: %w", err)
+		}
+
+		name := filepath.Base(path)
+		contentType := mime.TypeByExtension(filepath.Ext(name))
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		encodedName := mime.BEncoding.Encode("utf-8", name)
+
+		buf.WriteString("--" + boundary + "\r\n")
+		writeHeader("Content-Type", fmt.Sprintf("%s; name=\"%s\"", contentType, encodedName))
+		writeHeader("Content-Transfer-Encoding", "base64")
+		writeHeader("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", encodedName))
+		buf.WriteString("\r\n")
+		writeBase64(&buf, content)
+	}
+
+	buf.WriteString("--" + boundary + "--\r\n")
+	return buf.Bytes(), nil
+}
+
+// writeBase64 base64 with 76 chars per line (RFC 2045)
+func writeBase64(buf *bytes.Buffer, data []byte) {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		buf.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	buf.WriteString(encoded + "\r\n")
+}
+
+func stripCRLF(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+func addressOnly(s string) string {
+	if i := strings.LastIndex(s, "<"); i >= 0 {
+		return strings.TrimSuffix(s[i+1:], ">")
+	}
+	return strings.TrimSpace(s)
+}
+
+func domainOf(s string) string {
+	addr := addressOnly(s)
+	if i := strings.LastIndex(addr, "@"); i >= 0 {
+		return addr[i+1:]
+	}
+	return "noticat"
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
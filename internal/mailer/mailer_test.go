@@ -0,0 +1,400 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mailer
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"errors"
+	"io"
+	"math/big"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubServer a one connection smtp server on 127.0.0.1
+type stubServer struct {
+	addr string
+	// offer STARTTLS (tlsConfig required)
+	startTLS bool
+	// the listener itself is tls (smtps)
+	implicit bool
+	auth     bool
+	// verb (MAIL, RCPT, AUTH, DATA) to the reply replacing the 250
+	reject map[string]string
+
+	tlsConfig *tls.Config
+	done      chan struct{}
+
+	// what the client did
+	usedTLS bool
+	authLog string
+	from    string
+	rcpt    string
+	data    []byte
+}
+
+func newStubServer(t *testing.T, s *stubServer) *stubServer {
+	t.Helper()
+	if s.startTLS || s.implicit {
+		s.tlsConfig = testTLSConfig(t)
+	}
+
+	var ln net.Listener
+	var err error
+	if s.implicit {
+		ln, err = tls.Listen("tcp", "127.0.0.1:0", s.tlsConfig)
+	} else {
+		ln, err = net.Listen("tcp", "127.0.0.1:0")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	s.addr = ln.Addr().String()
+	s.done = make(chan struct{})
+	go func() {
+		defer close(s.done)
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(10 * time.Second))
+		s.usedTLS = s.implicit
+		s.serve(conn)
+	}()
+	return s
+}
+
+// wait the conversation is over, the recorded fields are safe to read after
+func (s *stubServer) wait(t *testing.T) {
+	t.Helper()
+	select {
+	case <-s.done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("smtp stub did not finish")
+	}
+}
+
+func (s *stubServer) serve(conn net.Conn) {
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 stub ESMTP")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		verb = strings.ToUpper(verb)
+		if reply, ok := s.reject[verb]; ok {
+			tp.PrintfLine("%s", reply)
+			continue
+		}
+
+		switch verb {
+		case "EHLO":
+			var ext []string
+			if s.startTLS && !s.usedTLS {
+				ext = append(ext, "STARTTLS")
+			}
+			if s.auth {
+				ext = append(ext, "AUTH PLAIN")
+			}
+			ext = append(ext, "8BITMIME")
+			tp.PrintfLine("250-stub")
+			for i, e := range ext {
+				if i == len(ext)-1 {
+					tp.PrintfLine("250 %s", e)
+				} else {
+					tp.PrintfLine("250-%s", e)
+				}
+			}
+		case "HELO":
+			tp.PrintfLine("250 stub")
+		case "STARTTLS":
+			tp.PrintfLine("220 go ahead")
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			tp = textproto.NewConn(conn)
+			s.usedTLS = true
+		case "AUTH":
+			_, resp, _ := strings.Cut(arg, " ")
+			decoded, _ := base64.StdEncoding.DecodeString(resp)
+			s.authLog = string(decoded)
+			tp.PrintfLine("235 ok")
+		case "MAIL":
+			s.from = arg
+			tp.PrintfLine("250 ok")
+		case "RCPT":
+			s.rcpt = arg
+			tp.PrintfLine("250 ok")
+		case "DATA":
+			tp.PrintfLine("354 go ahead")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.data = data
+			tp.PrintfLine("250 queued")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("502 unknown command")
+		}
+	}
+}
+
+// testTLSConfig a self signed cert for 127.0.0.1, trusted by Send while the
+// test runs
+func testTLSConfig(t *testing.T) *tls.Config {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "noticat test"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	rootCAs = pool
+	t.Cleanup(func() { rootCAs = nil })
+
+	return &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+}
+
+func testMail(t *testing.T) *Mail {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "附件.txt")
+	if err := os.WriteFile(path, []byte("attachment body"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return &Mail{
+		From:        "Noticat <bot@example.com>",
+		To:          "user@example.com",
+		Subject:     "新通知",
+		Body:        "<p>hello</p>",
+		Attachments: []string{path},
+		Headers: map[string]string{
+			"List-Unsubscribe": "<https://example.com/u>",
+			"X-Evil":           "a\r\nBcc: victim@example.com",
+		},
+	}
+}
+
+func TestSend(t *testing.T) {
+	cases := []struct {
+		name    string
+		stub    stubServer
+		scheme  string
+		account string
+		wantTLS bool
+	}{
+		{name: "plain", scheme: "smtp"},
+		{name: "plain auth", stub: stubServer{auth: true}, scheme: "smtp", account: "bot@example.com"},
+		{name: "starttls", stub: stubServer{startTLS: true, auth: true}, scheme: "smtp", account: "bot@example.com", wantTLS: true},
+		{name: "implicit tls", stub: stubServer{implicit: true, auth: true}, scheme: "smtps", account: "bot@example.com", wantTLS: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := newStubServer(t, &tc.stub)
+			m := testMail(t)
+			cfg := &Config{Server: tc.scheme + "://" + srv.addr, Account: tc.account, AuthCode: "secret", Timeout: 5 * time.Second}
+			if err := Send(cfg, m); err != nil {
+				t.Fatalf("Send: %v", err)
+			}
+			srv.wait(t)
+
+			if srv.usedTLS != tc.wantTLS {
+				t.Errorf("tls = %v, want %v", srv.usedTLS, tc.wantTLS)
+			}
+			wantAuth := ""
+			if tc.account != "" && tc.stub.auth {
+				wantAuth = "\x00bot@example.com\x00secret"
+			}
+			if srv.authLog != wantAuth {
+				t.Errorf("auth = %q, want %q", srv.authLog, wantAuth)
+			}
+			if !strings.HasPrefix(srv.from, "FROM:<bot@example.com>") || srv.rcpt != "TO:<user@example.com>" {
+				t.Errorf("envelope = %q %q", srv.from, srv.rcpt)
+			}
+			checkMessage(t, srv.data)
+		})
+	}
+}
+
+func checkMessage(t *testing.T, data []byte) {
+	t.Helper()
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("parse message: %v", err)
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "新通知" {
+		t.Errorf("subject = %q (%v)", subject, err)
+	}
+	if got := msg.Header.Get("List-Unsubscribe"); got != "<https://example.com/u>" {
+		t.Errorf("List-Unsubscribe = %q", got)
+	}
+	if got := msg.Header.Get("Bcc"); got != "" {
+		t.Errorf("header injected: Bcc = %q", got)
+	}
+	if msg.Header.Get("Message-ID") == "" || msg.Header.Get("Date") == "" {
+		t.Error("missing Message-ID or Date")
+	}
+
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	var parts []string
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(base64.NewDecoder(base64.StdEncoding, part))
+		if err != nil {
+			t.Fatal(err)
+		}
+		name, _ := new(mime.WordDecoder).DecodeHeader(part.FileName())
+		parts = append(parts, name+"="+string(body))
+	}
+	want := []string{"=<p>hello</p>", "附件.txt=attachment body"}
+	if strings.Join(parts, "|") != strings.Join(want, "|") {
+		t.Errorf("parts = %q, want %q", parts, want)
+	}
+}
+
+func TestSendRejected(t *testing.T) {
+	cases := []struct {
+		name      string
+		reject    map[string]string
+		want      error
+		transient bool
+		noAuth    bool
+	}{
+		{"bad recipient", map[string]string{"RCPT": "550 no such user"}, ErrRecipient, false, false},
+		{"mailbox busy", map[string]string{"RCPT": "450 mailbox busy"}, ErrTransient, true, false},
+		{"bad credentials", map[string]string{"AUTH": "535 authentication failed"}, ErrAuth, false, false},
+		{"sender refused", map[string]string{"MAIL": "553 sender not allowed"}, ErrPermanent, false, false},
+		{"try later", map[string]string{"DATA": "451 try again later"}, ErrTransient, true, false},
+		// credentials configured, but the server does not offer AUTH
+		{"no auth", nil, ErrPermanent, false, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := newStubServer(t, &stubServer{auth: !tc.noAuth, reject: tc.reject})
+			cfg := &Config{Server: "smtp://" + srv.addr, Account: "bot@example.com", AuthCode: "secret", Timeout: 5 * time.Second}
+			err := Send(cfg, testMail(t))
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("err = %v, want %v", err, tc.want)
+			}
+			if IsTransient(err) != tc.transient {
+				t.Errorf("IsTransient = %v, want %v", IsTransient(err), tc.transient)
+			}
+		})
+	}
+}
+
+func TestSendDialFailure(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	err = Send(&Config{Server: "smtp://" + addr, Timeout: time.Second}, &Mail{From: "a@b.c", To: "d@e.f"})
+	if !IsTransient(err) {
+		t.Fatalf("err = %v, want transient", err)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	cases := []struct {
+		stage string
+		err   error
+		want  error
+	}{
+		{"rcpt", &textproto.Error{Code: 550, Msg: "no such user"}, ErrRecipient},
+		{"rcpt", &textproto.Error{Code: 452, Msg: "too many recipients"}, ErrTransient},
+		{"mail", &textproto.Error{Code: 421, Msg: "closing"}, ErrTransient},
+		{"mail", &textproto.Error{Code: 554, Msg: "rejected"}, ErrPermanent},
+		{"data", &textproto.Error{Code: 552, Msg: "too big"}, ErrPermanent},
+		{"auth", &textproto.Error{Code: 535, Msg: "bad credentials"}, ErrAuth},
+		{"auth", &textproto.Error{Code: 454, Msg: "temporary"}, ErrTransient},
+		{"auth", errors.New("unencrypted connection"), ErrAuth},
+		{"dial", &net.OpError{Op: "dial", Err: errors.New("refused")}, ErrTransient},
+		{"data", io.ErrUnexpectedEOF, ErrTransient},
+	}
+
+	for _, tc := range cases {
+		err := classify(tc.stage, tc.err)
+		if !errors.Is(err, tc.want) {
+			t.Errorf("classify(%s, %v) = %v, want %v", tc.stage, tc.err, err, tc.want)
+		}
+		var smtpErr *Error
+		if !errors.As(err, &smtpErr) || smtpErr.Stage != tc.stage {
+			t.Errorf("classify(%s, %v) = %#v, want *Error of the stage", tc.stage, tc.err, err)
+		}
+		var tpErr *textproto.Error
+		if errors.As(tc.err, &tpErr) && smtpErr.Code != tpErr.Code {
+			t.Errorf("code = %d, want %d", smtpErr.Code, tpErr.Code)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mailer
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// smtp name to smtp url, keep the same as mail/src/send.cpp
+var serverURLMap = map[string]string{
+	"163":                       "smtps://smtp.163.com:465",
+	"126":                       "smtps://smtp.126.com:465",
+	"qq":                        "smtps://smtp.qq.com:465",
+	"yeah":                      "smtps://smtp.yeah.net:465",
+	"netease":                   "smtps://smtp.netease.com:465",
+	"sina":                      "smtps://smtp.sina.com:465",
+	"sinaVIP":                   "smtps://smtp.vip.sina.com:465",
+	"aliyun":                    "smtps://smtp.aliyun.com:465",
+	"sohu":                      "smtps://smtp.sohu.com:465",
+	"gmail":                     "smtps://smtp.gmail.com:465",
+	"outlook":                   "smtps://smtp-mail.outlook.com:587",
+	"hotmail":                   "smtps://smtp-mail.outlook.com:587",
+	"yahoo":                     "smtps://smtp.mail.yahoo.com:465",
+	"icloud":                    "smtps://smtp.mail.me.com:587",
+	"qq_enterprise":             "smtps://smtp.exmail.qq.com:465",
+	"netease_enterprise":        "smtps://smtp.qiye.163.com:465",
+	"ali_enterprise":            "smtps://smtp.mxhichina.com:465",
+	"tencent_enterprise_legacy": "smtps://smtp.exmail.qq.com:465",
+}
+
+type tlsMode int
+
+const (
+	tlsNone     tlsMode = iota // plain, upgrade with STARTTLS if the server offers it
+	tlsImplicit                // smtps on 465
+	tlsStartTLS                // STARTTLS is required
+)
+
+type endpoint struct {
+	host string
+	addr string
+	mode tlsMode
+}
+
+// resolveServer turn a short name (163, qq...) or url into a dial address.
+//
+//	smtps://host:465  implicit TLS
+//	smtps://host:587  STARTTLS (what curl does with the urls above)
+//	smtp://host:port  STARTTLS if offered, plain otherwise (local stand-in)
+func resolveServer(server string) (*endpoint, error) {
+	if u, ok := serverURLMap[server]; ok {
+		server = u
+	}
+	if !strings.Contains(server, "://") {
+		server = "smtps://" + server
+	}
+
+	u, err := url.Parse(server)
+	if err != nil {
+		return nil, fmt.Errorf("smtp server 格式错误: %w", err)
+	}
+
+	host := u.Hostname()
+	if host == "" {
+		return nil, fmt.Errorf("smtp server 缺少主机名: %s", server)
+	}
+	port := u.Port()
+
+	ep := &endpoint{host: host}
+	switch u.Scheme {
+	case "smtps":
+		if port == "" {
+			port = "465"
+		}
+		ep.mode = tlsImplicit
+		if port == "587" || port == "25" {
+			ep.mode = tlsStartTLS
+		}
+	case "smtp":
+		if port == "" {
+			port = "25"
+		}
+		ep.mode = tlsNone
+	default:
+		return nil, fmt.Errorf("不支持的 smtp scheme: %s", u.Scheme)
+	}
+
+	ep.addr = net.JoinHostPort(host, port)
+	return ep, nil
+}
@@ -24,11 +24,60 @@ import (
 	"gorm.io/gorm"
 )
 
+// delivery modes
+const (
+	DeliveryImmediate = "immediate"
+	DeliveryHourly    = "hourly"
+	DeliveryDaily     = "daily"
+	DeliveryWeekly    = "weekly"
+)
+
 type User struct {
 	gorm.Model
 	Username string `gorm:"unique;not null" json:"username"`
 	Password string `gorm:"not null" json:"password"`
 	Email    string `gorm:"unique;not null" json:"email"`
+
+	// default delivery of the subscriptions
+	DeliveryMode  string `gorm:"default:immediate" json:"delivery_mode"`
+	DigestTime    string `gorm:"default:08:00" json:"digest_time"`
+	DigestWeekday int    `gorm:"default:1" json:"digest_weekday"`
+
+	// IANA name, empty: NOTICAT_TIMEZONE
+	Timezone string `json:"timezone"`
+	// quiet hours "HH:MM", empty: no quiet hours. start > end crosses midnight
+	QuietStart        string `json:"quiet_start"`
+	QuietEnd          string `json:"quiet_end"`
+	WeekendQuietStart string `json:"weekend_quiet_start"`
+	WeekendQuietEnd   string `json:"weekend_quiet_end"`
+
+	// attachment policy, 0 / empty: the server default
+	AttachMaxFileMB  int    `json:"attach_max_file_mb"`
+	AttachMaxTotalMB int    `json:"attach_max_total_mb"`
+	AttachZip        string `json:"attach_zip"`
+
+	// secret of the feed urls, empty until asked for
+	FeedToken string `gorm:"index" json:"-"`
+}
+
+// Location the timezone of the user, fallback to the server one
+func (u *User) Location(fallback *time.Location) *time.Location {
+	if u.Timezone == "" {
+		return fallback
+	}
+	loc, err := time.LoadLocation(u.Timezone)
+	if err != nil {
+		return fallback
+	}
+	return loc
+}
+
+// QuietWindow quiet hours of a weekday
+func (u *User) QuietWindow(day time.Weekday) (start string, end string) {
+	if day == time.Saturday || day == time.Sunday {
+		return u.WeekendQuietStart, u.WeekendQuietEnd
+	}
+	return u.QuietStart, u.QuietEnd
 }
 
 type UserSubscription struct {
@@ -38,14 +87,61 @@ type UserSubscription struct {
 	TaskID  uint                 `gorm:"not null;uniqueIndex:idx_user_task"`
 	Task    FetchTask            `gorm:"foreignKey:TaskID"`
 	Filters []SubscriptionFilter `gorm:"foreignKey:SubscriptionID"`
+
+	// empty DeliveryMode: follow the user
+	DeliveryMode  string
+	DigestTime    string
+	DigestWeekday int
+	// when its last digest went out, nil: never
+	LastDigestAt *time.Time
+	// Urgent notices ignore quiet hours
+	Urgent bool
+	// Paused subscriptions get no notices until resumed
+	Paused bool `gorm:"default:false"`
+	// drop notices published more than MaxAgeDays ago, 0: keep all
+	MaxAgeDays int
+	// tell when a delivered notice is edited
+	NotifyUpdates bool `gorm:"default:true"`
+}
+
+// Delivery the effective delivery mode, digest time (HH:MM) and weekday (0: Sunday)
+func (s *UserSubscription) Delivery() (mode string, digestTime string, weekday int) {
+	if s.DeliveryMode != "" {
+		return s.DeliveryMode, s.DigestTime, s.DigestWeekday
+	}
+	if s.User.DeliveryMode != "" {
+		return s.User.DeliveryMode, s.User.DigestTime, s.User.DigestWeekday
+	}
+	return DeliveryImmediate, "", 0
+}
+
+// UserAddress an extra email address of the user, only saved once verified
+type UserAddress struct {
+	gorm.Model
+	UserID uint   `gorm:"uniqueIndex:idx_user_address" json:"-"`
+	Email  string `gorm:"uniqueIndex:idx_user_address;not null" json:"email"`
+	Label  string `json:"label"`
+}
+
+// SubscriptionAddress which addresses receive a subscription, AddressID 0 is
+// the account email. no rows: the account email only
+type SubscriptionAddress struct {
+	ID             uint `gorm:"primarykey"`
+	SubscriptionID uint `gorm:"uniqueIndex:idx_sub_address"`
+	AddressID      uint `gorm:"uniqueIndex:idx_sub_address"`
 }
 
 type SubscriptionFilter struct {
 	gorm.Model
-	SubscriptionID uint   `gorm:"index:idx_sub_pattern"`
-	Type           string `json:"type"`
-	Pattern        string `gorm:"index:idx_sub_pattern" json:"pattern"`
-	IgnoreCase     bool   `json:"ignore_case"`
+	SubscriptionID uint `gorm:"index:idx_sub_pattern"`
+	// keyword, regex or expr, Pattern is the expression for expr
+	Type       string `json:"type"`
+	Pattern    string `gorm:"index:idx_sub_pattern" json:"pattern"`
+	IgnoreCase bool   `json:"ignore_case"`
+	// what is matched, keyword and regex filters are converted to an expression
+	Expression string `json:"expression"`
+	// Muted filters match nothing
+	Muted bool `gorm:"default:false" json:"muted"`
 }
 
 type UserNotice struct {
@@ -53,6 +149,37 @@ type UserNotice struct {
 	UserID      uint   `gorm:"uniqueIndex:idx_user_content"`
 	Client      string `gorm:"uniqueIndex:idx_user_content"`
 	ContentHash string `gorm:"uniqueIndex:idx_user_content"`
+	// set once a channel accepted it, nil: seen only
+	DeliveredAt *time.Time
+	// set once it matched a subscription, 0: seen only
+	SubscriptionID uint `gorm:"index"`
+	NoticeID       uint
+
+	// inbox state
+	ReadAt     *time.Time
+	Starred    bool `gorm:"default:false"`
+	ArchivedAt *time.Time
+}
+
+// Notice the content of a matched notice, shared by the users who got it
+type Notice struct {
+	gorm.Model
+	Client      string `gorm:"uniqueIndex:idx_notice_content"`
+	ContentHash string `gorm:"uniqueIndex:idx_notice_content"`
+	Title       string
+	URL         string
+	Date        string
+	Deadline    string
+	// Date parsed, nil: unknown
+	PublishedAt *time.Time `gorm:"index"`
+	// detail html, empty until fetched
+	Body     string
+	DetailAt *time.Time
+	// text of the detail with the attachment names and its sha256, to find edits
+	Text        string
+	Fingerprint string
+	// last time the detail was fetched again
+	CheckedAt *time.Time `gorm:"index"`
 }
 
 type FetchTask struct {
@@ -63,3 +190,105 @@ type FetchTask struct {
 	Extra       string
 	LastFetchAt time.Time
 }
+
+// NotifyTarget an extra delivery channel besides the account email
+type NotifyTarget struct {
+	gorm.Model
+	UserID uint `gorm:"index" json:"-"`
+	// 0 means every subscription of the user
+	SubscriptionID uint       `gorm:"index" json:"subscription_id"`
+	Channel        string     `gorm:"not null" json:"channel"`
+	Address        string     `gorm:"not null" json:"address"`
+	Secret         string     `json:"-"`
+	LastStatus     string     `json:"last_status"`
+	LastError      string     `json:"last_error"`
+	LastSentAt     *time.Time `json:"last_sent_at"`
+}
+
+// why a notice is pending
+const (
+	PendingDigest = "digest"
+	PendingQuiet  = "quiet"
+)
+
+// PendingNotice a matched notice waiting for the digest or the end of quiet hours
+type PendingNotice struct {
+	gorm.Model
+	UserID         uint   `gorm:"index"`
+	SubscriptionID uint   `gorm:"index"`
+	Reason         string `gorm:"index;default:digest"`
+	UserNoticeID   uint
+	Client         string
+	Title          string
+	URL            string
+	Date           string
+	// an edit of a delivered notice: the diff of its text (html), empty for a
+	// new notice
+	Diff string
+}
+
+// delivery job status
+const (
+	JobPending = "pending"
+	JobSending = "sending"
+	JobSent    = "sent"
+	JobDead    = "dead"
+)
+
+// DeliveryJob one message to one target, drained by the delivery workers.
+// it is also the delivery record shown to the user
+type DeliveryJob struct {
+	gorm.Model
+	UserID         uint `gorm:"index"`
+	SubscriptionID uint `gorm:"index"`
+	// comma separated UserNotice ids covered by the message (a digest has many)
+	UserNoticeIDs string
+	// ContentHash of the notice, empty for digests
+	NoticeHash string `gorm:"index"`
+	Title      string
+	// NotifyTarget id, 0 for the account email
+	TargetID uint
+	Channel  string
+	Address  string
+	// json of notifier.Message
+	Payload string
+	// attachments directory, removed when no job needs it
+	SpoolDir      string
+	Status        string    `gorm:"index;default:pending"`
+	Attempts      int       `gorm:"default:0"`
+	MaxAttempts   int       `gorm:"default:8"`
+	NextAttemptAt time.Time `gorm:"index"`
+	LastError     string
+	DeliveredAt   *time.Time
+}
+
+// DeliveryAttempt the result of every try of a DeliveryJob
+type DeliveryAttempt struct {
+	ID        uint `gorm:"primarykey"`
+	JobID     uint `gorm:"index"`
+	Attempt   int
+	Success   bool
+	Error     string
+	CreatedAt time.Time
+}
+
+// StoredFile a blob of the content-addressed attachment store
+type StoredFile struct {
+	gorm.Model
+	Hash string `gorm:"uniqueIndex;not null"`
+	Size int64
+	// last time it was attached or linked, for retention
+	LastUsedAt time.Time `gorm:"index"`
+	// the last download link given out expires then, kept until that
+	LinkedUntil *time.Time `gorm:"index"`
+}
+
+// NoticeFile an attachment of a notice, Name is the original file name
+type NoticeFile struct {
+	gorm.Model
+	Client     string `gorm:"uniqueIndex:idx_notice_file"`
+	NoticeHash string `gorm:"uniqueIndex:idx_notice_file"`
+	SourceURL  string `gorm:"uniqueIndex:idx_notice_file"`
+	Name       string
+	FileHash   string `gorm:"index"`
+}
@@ -0,0 +1,339 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"noticat/pkg/common"
+	"noticat/pkg/global"
+)
+
+// Telegram: Address is the chat id, Secret the bot token.
+// Discord / Slack: Address is the webhook url.
+const (
+	ChannelTelegram = "telegram"
+	ChannelDiscord  = "discord"
+	ChannelSlack    = "slack"
+)
+
+// message size limits (characters)
+const (
+	telegramMaxChars     = 4096
+	discordMaxChars      = 4096
+	slackMaxChars        = 3000
+	telegramMaxFileBytes = 50 << 20
+	discordMaxFileBytes  = 25 << 20
+	discordMaxFiles      = 10
+)
+
+var (
+	chatHTTPClient = newTargetHTTPClient(60 * time.Second)
+	// the bot api is set by the server, it may be a local one
+	telegramHTTPClient = &http.Client{Timeout: 60 * time.Second}
+)
+
+type TelegramNotifier struct {
+	// BaseURL of the bot api, NOTICAT_TELEGRAM_API by default
+	BaseURL string
+}
+
+func (t *TelegramNotifier) Validate(target *Target) error {
+	if target.Address == "" || target.Secret == "" {
+		return fmt.Errorf("telegram 需要 chat id (address) 和 bot token (secret)")
+	}
+	return nil
+}
+
+func (t *TelegramNotifier) Send(target *Target, msg *Message) error {
+	header := "<b>" + html.EscapeString(common.TruncateRunes(botTitle(msg), 256)) + "</b>\n\n"
+	var source string
+	if msg.URL != "" {
+		source = fmt.Sprintf("\n\n<a href=\"%s\">查看原文</a>", html.EscapeString(msg.URL))
+	}
+	// the limit counts characters after entities parsing, counting the markup
+	// as well only makes it safer
+	room := telegramMaxChars - len([]rune(header)) - len([]rune(source))
+
+	// telegram only knows a few tags, send plain text instead of the detail html
+	text := common.HTMLToText(msg.Body)
+	if text == msg.Title {
+		text = ""
+	}
+	// the links get what the body leaves, but at least half
+	links := telegramLinks(msg.Links, room-min(len([]rune(text)), room/2))
+	room -= len([]rune(links))
+	body := html.EscapeString(common.TruncateRunes(text, max(room, 0)))
+
+	err := t.call(target, "sendMessage", map[string]any{
+		"chat_id":                  target.Address,
+		"text":                     header + body + links + source,
+		"parse_mode":               "HTML",
+		"disable_web_page_preview": true,
+	})
+	if err != nil {
+		return err
+	}
+
+	// the message is out, failing now would send it again on retry
+	for _, path := range msg.Attachments {
+		if info, err := os.Stat(path); err != nil || info.Size() > telegramMaxFileBytes {
+			continue
+		}
+		if err := t.sendDocument(target, path); err != nil {
+			log.Printf("[Telegram] 发送附件 %s 失败: %v", filepath.Base(path), err)
+		}
+	}
+	return nil
+}
+
+// telegramLinks the attachment lines fitting in room, the rest is counted
+func telegramLinks(links []Link, room int) string {
+	var b strings.Builder
+	for i, l := range links {
+		line := fmt.Sprintf("\n📎 <a href=\"%s\">%s</a>", html.EscapeString(l.URL), html.EscapeString(l.Title))
+		rest := ""
+		if i < len(links)-1 {
+			// what is left must still fit when the next one does not
+			rest = fmt.Sprintf("\n📎 另有 %d 个附件", len(links)-i-1)
+		}
+		if len([]rune(b.String()))+len([]rune(line))+len([]rune(rest)) > room {
+			more := fmt.Sprintf("\n📎 另有 %d 个附件", len(links)-i)
+			if len([]rune(b.String()))+len([]rune(more)) <= room {
+				b.WriteString(more)
+			}
+			break
+		}
+		b.WriteString(line)
+	}
+	return b.String()
+}
+
+func (t *TelegramNotifier) endpoint(target *Target, method string) string {
+	base := t.BaseURL
+	if base == "" {
+		base = global.TelegramAPIBase
+	}
+	return fmt.Sprintf("%s/bot%s/%s", strings.TrimRight(base, "/"), target.Secret, method)
+}
+
+func (t *TelegramNotifier) call(target *Target, method string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("消息序列化失败: %w", err)
+	}
+	resp, err := telegramHTTPClient.Post(t.endpoint(target, method), "application/json", bytes.NewReader(body))
+	if err != nil {
+		// the url contains the bot token, do not leak it into logs
+		return fmt.Errorf("telegram %s 请求失败", method)
+	}
+	return checkTelegram(method, resp)
+}
+
+func (t *TelegramNotifier) sendDocument(target *Target, path string) error {
+	body, contentType, err := multipartBody(map[string]string{"chat_id": target.Address}, "document", []string{path})
+	if err != nil {
+		return err
+	}
+	resp, err := telegramHTTPClient.Post(t.endpoint(target, "sendDocument"), contentType, body)
+	if err != nil {
+		return fmt.Errorf("telegram sendDocument 请求失败")
+	}
+	return checkTelegram("sendDocument", resp)
+}
+
+// checkTelegram a wrong token, chat or request is Permanent, the bot api
+// tells with error_code
+func checkTelegram(method string, resp *http.Response) error {
+	defer resp.Body.Close()
+
+	var r struct {
+		OK          bool   `json:"ok"`
+		ErrorCode   int    `json:"error_code"`
+		Description string `json:"description"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err := json.Unmarshal(data, &r); err != nil {
+		return statusError(resp.StatusCode, fmt.Errorf("telegram %s 返回状态码 %d", method, resp.StatusCode))
+	}
+	if !r.OK {
+		err := fmt.Errorf("telegram %s 失败: %s", method, r.Description)
+		switch r.ErrorCode {
+		case http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+			return Permanent(err)
+		}
+		return err
+	}
+	return nil
+}
+
+type DiscordNotifier struct{}
+
+func (DiscordNotifier) Validate(target *Target) error {
+	return validateHTTPURL(target.Address)
+}
+
+func (DiscordNotifier) Send(target *Target, msg *Message) error {
+	// a byte limit is always within the character limit
+	description := botMarkdown(msg, "", discordMaxChars)
+
+	embed := map[string]any{
+		"title":       common.TruncateRunes(botTitle(msg), 256),
+		"description": description,
+	}
+	if msg.URL != "" {
+		embed["url"] = msg.URL
+	}
+	if msg.Client != "" {
+		embed["footer"] = map[string]string{"text": msg.Client}
+	}
+	payload, err := json.Marshal(map[string]any{"embeds": []any{embed}})
+	if err != nil {
+		return fmt.Errorf("消息序列化失败: %w", err)
+	}
+
+	var files []string
+	for _, path := range msg.Attachments {
+		if info, err := os.Stat(path); err == nil && info.Size() <= discordMaxFileBytes && len(files) < discordMaxFiles {
+			files = append(files, path)
+		}
+	}
+
+	var resp *http.Response
+	if len(files) == 0 {
+		resp, err = chatHTTPClient.Post(target.Address, "application/json", bytes.NewReader(payload))
+	} else {
+		body, contentType, buildErr := multipartBody(map[string]string{"payload_json": string(payload)}, "files[%d]", files)
+		if buildErr != nil {
+			return buildErr
+		}
+		resp, err = chatHTTPClient.Post(target.Address, contentType, body)
+	}
+	if err != nil {
+		return err
+	}
+	return checkStatus("discord", resp)
+}
+
+// SlackNotifier incoming webhooks can not upload files, attachments are sent as links
+type SlackNotifier struct{}
+
+func (SlackNotifier) Validate(target *Target) error {
+	return validateHTTPURL(target.Address)
+}
+
+func (SlackNotifier) Send(target *Target, msg *Message) error {
+	escape := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace
+
+	header := "*" + escape(botTitle(msg)) + "*\n"
+	var footer strings.Builder
+	for _, l := range msg.Links {
+		footer.WriteString(fmt.Sprintf("\n:paperclip: <%s|%s>", l.URL, escape(l.Title)))
+	}
+	if msg.URL != "" {
+		footer.WriteString(fmt.Sprintf("\n<%s|查看原文>", msg.URL))
+	}
+
+	text := common.HTMLToText(msg.Body)
+	if text == msg.Title {
+		text = ""
+	}
+	room := slackMaxChars - len([]rune(header)) - len([]rune(footer.String())) - 16
+	mrkdwn := header + escape(common.TruncateRunes(text, max(room, 0))) + footer.String()
+
+	payload, err := json.Marshal(map[string]any{
+		// fallback for notifications
+		"text": botTitle(msg),
+		"blocks": []any{
+			map[string]any{
+				"type": "section",
+				"text": map[string]string{"type": "mrkdwn", "text": mrkdwn},
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("消息序列化失败: %w", err)
+	}
+
+	resp, err := chatHTTPClient.Post(target.Address, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	return checkStatus("slack", resp)
+}
+
+func checkStatus(name string, resp *http.Response) error {
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return statusError(resp.StatusCode, fmt.Errorf("%s 返回状态码 %d: %s", name, resp.StatusCode, string(data)))
+	}
+	return nil
+}
+
+// multipartBody fields + files, fileField may contain %d for the file index
+func multipartBody(fields map[string]string, fileField string, files []string) (io.Reader, string, error) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+
+	for k, v := range fields {
+		if err := w.WriteField(k, v); err != nil {
+			return nil, "", err
+		}
+	}
+
+	for i, path := range files {
+		name := fileField
+		if strings.Contains(name, "%d") {
+			name = fmt.Sprintf(fileField, i)
+		}
+
+		part, err := w.CreateFormFile(name, filepath.Base(path))
+		if err != nil {
+			return nil, "", err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, "", fmt.Errorf("读取附件失败: %w", err)
+		}
+		_, err = io.Copy(part, f)
+		f.Close()
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, "", err
+	}
+	return &buf, w.FormDataContentType(), nil
+}
+
+func init() {
+	Register(ChannelTelegram, &TelegramNotifier{})
+	Register(ChannelDiscord, DiscordNotifier{})
+	Register(ChannelSlack, SlackNotifier{})
+}
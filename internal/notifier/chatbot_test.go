@@ -0,0 +1,293 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"noticat/pkg/global"
+)
+
+// allowLocalTargets the fake servers listen on loopback
+func allowLocalTargets(t *testing.T) {
+	t.Helper()
+	saved := global.AllowPrivateTargets
+	global.AllowPrivateTargets = "true"
+	t.Cleanup(func() { global.AllowPrivateTargets = saved })
+}
+
+// fakeRequest what a fake api server received
+type fakeRequest struct {
+	Path        string
+	ContentType string
+	JSON        map[string]any
+	Fields      map[string]string
+	Files       []string
+}
+
+// fakeServer record the requests, reply answers with the status and body
+func fakeServer(t *testing.T, reply func(r *http.Request) (int, string)) (*httptest.Server, func() []fakeRequest) {
+	t.Helper()
+	var (
+		mu   sync.Mutex
+		reqs []fakeRequest
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := fakeRequest{Path: r.URL.Path, ContentType: r.Header.Get("Content-Type"), Fields: map[string]string{}}
+		if strings.HasPrefix(got.ContentType, "multipart/") {
+			if err := r.ParseMultipartForm(1 << 20); err == nil {
+				for k, v := range r.MultipartForm.Value {
+					got.Fields[k] = v[0]
+				}
+				for _, files := range r.MultipartForm.File {
+					for _, f := range files {
+						got.Files = append(got.Files, f.Filename)
+					}
+				}
+			}
+		} else {
+			data, _ := io.ReadAll(r.Body)
+			json.Unmarshal(data, &got.JSON)
+		}
+		mu.Lock()
+		reqs = append(reqs, got)
+		mu.Unlock()
+
+		code, body := reply(r)
+		w.WriteHeader(code)
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []fakeRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]fakeRequest(nil), reqs...)
+	}
+}
+
+func writeAttachment(t *testing.T, name string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(name), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestTelegramSend(t *testing.T) {
+	srv, requests := fakeServer(t, func(r *http.Request) (int, string) {
+		if strings.HasSuffix(r.URL.Path, "/sendDocument") {
+			// a failed document does not fail the sent message
+			return http.StatusBadRequest, `{"ok":false,"error_code":400,"description":"Bad Request: file is empty"}`
+		}
+		return http.StatusOK, `{"ok":true,"result":{}}`
+	})
+
+	tg := &TelegramNotifier{BaseURL: srv.URL + "/"}
+	target := &Target{Channel: ChannelTelegram, Address: "42", Secret: "123:token"}
+	msg := &Message{
+		Title:       "关于 <奖学金> 的通知",
+		Body:        "<p>请于周五前提交</p>",
+		URL:         "https://example.com/1?a=1&b=2",
+		Links:       []Link{{Title: "名单.pdf", URL: "https://example.com/files/1"}},
+		Attachments: []string{writeAttachment(t, "名单.pdf"), writeAttachment(t, "表格.xlsx")},
+	}
+	if err := tg.Send(target, msg); err != nil {
+		t.Fatal(err)
+	}
+
+	reqs := requests()
+	if len(reqs) != 3 {
+		t.Fatalf("got %d requests, want sendMessage and 2 sendDocument", len(reqs))
+	}
+	if reqs[0].Path != "/bot123:token/sendMessage" {
+		t.Errorf("path = %s", reqs[0].Path)
+	}
+	text, _ := reqs[0].JSON["text"].(string)
+	for _, want := range []string{
+		"<b>关于 &lt;奖学金&gt; 的通知</b>",
+		"请于周五前提交",
+		`📎 <a href="https://example.com/files/1">名单.pdf</a>`,
+		`<a href="https://example.com/1?a=1&amp;b=2">查看原文</a>`,
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text %q does not contain %q", text, want)
+		}
+	}
+	if reqs[0].JSON["chat_id"] != "42" || reqs[0].JSON["parse_mode"] != "HTML" {
+		t.Errorf("payload = %v", reqs[0].JSON)
+	}
+	for i, name := range []string{"名单.pdf", "表格.xlsx"} {
+		doc := reqs[i+1]
+		if doc.Path != "/bot123:token/sendDocument" || doc.Fields["chat_id"] != "42" ||
+			len(doc.Files) != 1 || doc.Files[0] != name {
+			t.Errorf("document %d = %+v", i, doc)
+		}
+	}
+}
+
+func TestDiscordSend(t *testing.T) {
+	allowLocalTargets(t)
+	srv, requests := fakeServer(t, func(r *http.Request) (int, string) { return http.StatusNoContent, "" })
+
+	target := &Target{Channel: ChannelDiscord, Address: srv.URL + "/api/webhooks/1/abc"}
+	msg := &Message{Title: "讲座通知", Body: "<p>周三下午</p>", URL: "https://example.com/2", Client: "jwc"}
+	if err := (DiscordNotifier{}).Send(target, msg); err != nil {
+		t.Fatal(err)
+	}
+	msg.Attachments = []string{writeAttachment(t, "海报.png")}
+	if err := (DiscordNotifier{}).Send(target, msg); err != nil {
+		t.Fatal(err)
+	}
+
+	reqs := requests()
+	if len(reqs) != 2 {
+		t.Fatalf("got %d requests, want 2", len(reqs))
+	}
+	embeds, _ := reqs[0].JSON["embeds"].([]any)
+	if len(embeds) != 1 {
+		t.Fatalf("payload = %v", reqs[0].JSON)
+	}
+	embed := embeds[0].(map[string]any)
+	if embed["title"] != "讲座通知" || embed["url"] != "https://example.com/2" ||
+		!strings.Contains(embed["description"].(string), "周三下午") {
+		t.Errorf("embed = %v", embed)
+	}
+	if footer, _ := embed["footer"].(map[string]any); footer["text"] != "jwc" {
+		t.Errorf("footer = %v", embed["footer"])
+	}
+
+	// with files the embed goes in payload_json
+	if len(reqs[1].Files) != 1 || reqs[1].Files[0] != "海报.png" ||
+		!strings.Contains(reqs[1].Fields["payload_json"], "讲座通知") {
+		t.Errorf("multipart = %+v", reqs[1])
+	}
+}
+
+func TestSlackSend(t *testing.T) {
+	allowLocalTargets(t)
+	srv, requests := fakeServer(t, func(r *http.Request) (int, string) { return http.StatusOK, "ok" })
+
+	target := &Target{Channel: ChannelSlack, Address: srv.URL + "/services/T/B/x"}
+	msg := &Message{
+		Title:       "A & B <通知>",
+		Body:        "正文",
+		URL:         "https://example.com/3",
+		Links:       []Link{{Title: "附件", URL: "https://example.com/files/3"}},
+		Attachments: []string{writeAttachment(t, "not-sent.pdf")},
+	}
+	if err := (SlackNotifier{}).Send(target, msg); err != nil {
+		t.Fatal(err)
+	}
+
+	reqs := requests()
+	if len(reqs) != 1 || reqs[0].JSON == nil {
+		t.Fatalf("requests = %+v", reqs)
+	}
+	if reqs[0].JSON["text"] != "A & B <通知>" {
+		t.Errorf("fallback text = %v", reqs[0].JSON["text"])
+	}
+	blocks, _ := reqs[0].JSON["blocks"].([]any)
+	if len(blocks) != 1 {
+		t.Fatalf("blocks = %v", reqs[0].JSON["blocks"])
+	}
+	mrkdwn := blocks[0].(map[string]any)["text"].(map[string]any)["text"].(string)
+	for _, want := range []string{
+		"*A &amp; B &lt;通知&gt;*",
+		"正文",
+		":paperclip: <https://example.com/files/3|附件>",
+		"<https://example.com/3|查看原文>",
+	} {
+		if !strings.Contains(mrkdwn, want) {
+			t.Errorf("mrkdwn %q does not contain %q", mrkdwn, want)
+		}
+	}
+}
+
+func TestChatBotErrors(t *testing.T) {
+	allowLocalTargets(t)
+
+	cases := []struct {
+		name      string
+		channel   string
+		status    int
+		body      string
+		permanent bool
+	}{
+		{"telegram revoked token", ChannelTelegram, 401, `{"ok":false,"error_code":401,"description":"Unauthorized"}`, true},
+		{"telegram bot blocked", ChannelTelegram, 403, `{"ok":false,"error_code":403,"description":"Forbidden: bot was blocked by the user"}`, true},
+		{"telegram chat not found", ChannelTelegram, 400, `{"ok":false,"error_code":400,"description":"Bad Request: chat not found"}`, true},
+		{"telegram wrong path", ChannelTelegram, 404, `{"ok":false,"error_code":404,"description":"Not Found"}`, true},
+		{"telegram flood", ChannelTelegram, 429, `{"ok":false,"error_code":429,"description":"Too Many Requests: retry after 5"}`, false},
+		{"telegram down", ChannelTelegram, 502, `<html>Bad Gateway</html>`, false},
+		{"telegram proxy refuses", ChannelTelegram, 403, `forbidden`, true},
+		{"discord deleted webhook", ChannelDiscord, 404, `{"message":"Unknown Webhook","code":10015}`, true},
+		{"discord rate limited", ChannelDiscord, 429, `{"retry_after":1.5}`, false},
+		{"discord down", ChannelDiscord, 503, ``, false},
+		{"slack removed hook", ChannelSlack, 404, `no_service`, true},
+		{"slack archived channel", ChannelSlack, 410, `channel_is_archived`, true},
+		{"slack down", ChannelSlack, 500, ``, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv, _ := fakeServer(t, func(r *http.Request) (int, string) { return tc.status, tc.body })
+
+			var n Notifier
+			target := &Target{Channel: tc.channel, Address: srv.URL + "/hook"}
+			switch tc.channel {
+			case ChannelTelegram:
+				n = &TelegramNotifier{BaseURL: srv.URL}
+				target.Address, target.Secret = "42", "123:token"
+			case ChannelDiscord:
+				n = DiscordNotifier{}
+			case ChannelSlack:
+				n = SlackNotifier{}
+			}
+
+			err := n.Send(target, &Message{Title: "通知"})
+			if err == nil {
+				t.Fatal("Send succeeded")
+			}
+			if IsPermanent(err) != tc.permanent {
+				t.Errorf("IsPermanent(%v) = %v, want %v", err, IsPermanent(err), tc.permanent)
+			}
+			if tc.channel == ChannelTelegram && strings.Contains(err.Error(), target.Secret) {
+				t.Errorf("error leaks the bot token: %v", err)
+			}
+		})
+	}
+}
+
+func TestTelegramUnreachable(t *testing.T) {
+	srv, _ := fakeServer(t, func(r *http.Request) (int, string) { return http.StatusOK, "" })
+	base := srv.URL
+	srv.Close()
+
+	err := (&TelegramNotifier{BaseURL: base}).Send(&Target{Address: "42", Secret: "123:token"}, &Message{Title: "通知"})
+	if err == nil || IsPermanent(err) {
+		t.Errorf("err = %v, want a retryable error", err)
+	}
+	if err != nil && strings.Contains(err.Error(), "123:token") {
+		t.Errorf("error leaks the bot token: %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"errors"
+	"fmt"
+	"html"
+
+	"noticat/internal/bridge"
+	"noticat/internal/mailer"
+	"noticat/pkg/global"
+)
+
+const ChannelEmail = "email"
+
+// EmailNotifier send mail with mail/bin/send or internal/mailer (NOTICAT_MAIL_SENDER)
+type EmailNotifier struct{}
+
+func (EmailNotifier) Send(target *Target, msg *Message) error {
+	if global.MailSender == "go" {
+		return sendWithMailer(target, msg)
+	}
+
+	attachments := msg.Attachments
+	if attachments == nil {
+		attachments = []string{}
+	}
+
+	var headers []string
+	for key, value := range unsubscribeHeaders(msg) {
+		headers = append(headers, key+": "+value)
+	}
+
+	err := bridge.SendMail(&bridge.SendOptions{
+		SMTPServer:  global.SMTPSERVER,
+		Account:     global.ACCOUNT,
+		AuthCode:    global.AUTHCODE,
+		Subject:     msg.Subject,
+		Body:        emailBody(msg),
+		From:        global.ACCOUNT,
+		To:          target.Address,
+		Attachments: attachments,
+		Headers:     headers,
+	})
+	var mailErr *bridge.MailError
+	if errors.As(err, &mailErr) && mailErr.Permanent() {
+		return Permanent(err)
+	}
+	return err
+}
+
+// unsubscribeHeaders RFC 2369 and RFC 8058 one-click unsubscribe
+func unsubscribeHeaders(msg *Message) map[string]string {
+	if msg.UnsubscribeURL == "" {
+		return nil
+	}
+	return map[string]string{
+		"List-Unsubscribe":      "<" + msg.UnsubscribeURL + ">",
+		"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+	}
+}
+
+// emailBody the body with an unsubscribe footer
+func emailBody(msg *Message) string {
+	if msg.UnsubscribeURL == "" {
+		return msg.Body
+	}
+	return msg.Body + fmt.Sprintf(
+		`<hr><p style="color:#999;font-size:12px">不想再收到此订阅的通知？<a href="%s">退订或暂停</a></p>`,
+		html.EscapeString(msg.UnsubscribeURL),
+	)
+}
+
+// sendWithMailer one attempt, the delivery queue retries the transient failures
+func sendWithMailer(target *Target, msg *Message) error {
+	cfg := &mailer.Config{
+		Server:   global.SMTPSERVER,
+		Account:  global.ACCOUNT,
+		AuthCode: global.AUTHCODE,
+	}
+	m := &mailer.Mail{
+		From:        global.ACCOUNT,
+		To:          target.Address,
+		Subject:     msg.Subject,
+		Body:        emailBody(msg),
+		Attachments: msg.Attachments,
+		Headers:     unsubscribeHeaders(msg),
+	}
+
+	err := mailer.Send(cfg, m)
+	if errors.Is(err, mailer.ErrRecipient) || errors.Is(err, mailer.ErrPermanent) {
+		return Permanent(err)
+	}
+	return err
+}
+
+func init() {
+	Register(ChannelEmail, EmailNotifier{})
+}
@@ -0,0 +1,256 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"noticat/pkg/common"
+)
+
+// group robots of DingTalk, Feishu/Lark and WeCom.
+// target.Address is the robot webhook url, target.Secret the sign secret (if enabled)
+const (
+	ChannelDingTalk = "dingtalk"
+	ChannelFeishu   = "feishu"
+	ChannelWeCom    = "wecom"
+)
+
+// message size limits of each platform (bytes), keep some room for the json wrapper
+const (
+	dingTalkMaxBytes = 18000
+	feishuMaxBytes   = 18000
+	weComMaxBytes    = 4000
+)
+
+var botHTTPClient = newTargetHTTPClient(10 * time.Second)
+
+// botResponse DingTalk and WeCom use errcode, Feishu uses code
+type botResponse struct {
+	ErrCode *int   `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+	Code    *int   `json:"code"`
+	Msg     string `json:"msg"`
+}
+
+func postBot(endpoint string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("消息序列化失败: %w", err)
+	}
+
+	resp, err := botHTTPClient.Post(endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode != http.StatusOK {
+		return statusError(resp.StatusCode, fmt.Errorf("机器人返回状态码 %d: %s", resp.StatusCode, string(data)))
+	}
+
+	var r botResponse
+	if err := json.Unmarshal(data, &r); err != nil {
+		return fmt.Errorf("无法解析机器人响应: %s", string(data))
+	}
+	if r.ErrCode != nil && *r.ErrCode != 0 {
+		return botError(*r.ErrCode, r.ErrMsg)
+	}
+	if r.Code != nil && *r.Code != 0 {
+		return botError(*r.Code, r.Msg)
+	}
+	return nil
+}
+
+// permanentBotCodes the robot is gone or the target is misconfigured,
+// retrying will not help. Rate limits and server errors are not here
+var permanentBotCodes = map[int]bool{
+	// DingTalk: invalid or unknown access_token, security settings
+	// (keyword, sign, ip) not matched, robot disabled
+	300001: true,
+	300005: true,
+	310000: true,
+	400101: true,
+	400102: true,
+	// Feishu: invalid webhook token or params, sign not matched,
+	// ip not allowed, keyword not found
+	19001: true,
+	19002: true,
+	19021: true,
+	19022: true,
+	19024: true,
+	// WeCom: invalid webhook url, the key was removed
+	93000: true,
+}
+
+func botError(code int, msg string) error {
+	err := fmt.Errorf("机器人返回错误 %d: %s", code, msg)
+	if permanentBotCodes[code] {
+		return Permanent(err)
+	}
+	return err
+}
+
+// botMarkdown body + attachment links + source link, cut to limit bytes.
+// the links at the end are kept, only the body is truncated
+func botMarkdown(msg *Message, header string, limit int) string {
+	var footer strings.Builder
+	if len(msg.Links) > 0 {
+		footer.WriteString("\n\n**附件**")
+		for _, l := range msg.Links {
+			footer.WriteString(fmt.Sprintf("\n- [%s](%s)", l.Title, l.URL))
+		}
+	}
+	if msg.URL != "" {
+		footer.WriteString(fmt.Sprintf("\n\n[查看原文](%s)", msg.URL))
+	}
+
+	body := common.HTMLToMarkdown(msg.Body)
+	if body == msg.Title {
+		body = ""
+	}
+
+	room := limit - len(header) - footer.Len()
+	if room < 0 {
+		// too many links, drop them
+		return common.TruncateBytes(header+body, limit)
+	}
+	return header + common.TruncateBytes(body, room) + footer.String()
+}
+
+func botTitle(msg *Message) string {
+	if msg.Title != "" {
+		return msg.Title
+	}
+	return msg.Subject
+}
+
+type DingTalkNotifier struct{}
+
+func (DingTalkNotifier) Validate(target *Target) error {
+	return validateHTTPURL(target.Address)
+}
+
+func (DingTalkNotifier) Send(target *Target, msg *Message) error {
+	endpoint := target.Address
+	if target.Secret != "" {
+		timestamp := time.Now().UnixMilli()
+		sep := "&"
+		if !strings.Contains(endpoint, "?") {
+			sep = "?"
+		}
+		endpoint += fmt.Sprintf("%stimestamp=%d&sign=%s", sep, timestamp, url.QueryEscape(dingTalkSign(target.Secret, timestamp)))
+	}
+
+	title := botTitle(msg)
+	text := botMarkdown(msg, "### "+title+"\n\n", dingTalkMaxBytes)
+
+	payload := map[string]any{
+		"msgtype": "markdown",
+		"markdown": map[string]string{
+			"title": common.ShortenTitle(title),
+			"text":  text,
+		},
+	}
+	return postBot(endpoint, payload)
+}
+
+// dingTalkSign base64(HmacSHA256(secret, timestamp + "\n" + secret))
+func dingTalkSign(secret string, timestamp int64) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "\n" + secret))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+type FeishuNotifier struct{}
+
+func (FeishuNotifier) Validate(target *Target) error {
+	return validateHTTPURL(target.Address)
+}
+
+func (FeishuNotifier) Send(target *Target, msg *Message) error {
+	elements := []any{
+		map[string]any{
+			"tag": "div",
+			"text": map[string]string{
+				"tag":     "lark_md",
+				"content": botMarkdown(msg, "", feishuMaxBytes),
+			},
+		},
+	}
+
+	payload := map[string]any{
+		"msg_type": "interactive",
+		"card": map[string]any{
+			"header": map[string]any{
+				"title": map[string]string{
+					"tag":     "plain_text",
+					"content": botTitle(msg),
+				},
+				"template": "blue",
+			},
+			"elements": elements,
+		},
+	}
+
+	if target.Secret != "" {
+		timestamp := time.Now().Unix()
+		payload["timestamp"] = strconv.FormatInt(timestamp, 10)
+		payload["sign"] = feishuSign(target.Secret, timestamp)
+	}
+
+	return postBot(target.Address, payload)
+}
+
+// feishuSign base64(HmacSHA256(key = timestamp + "\n" + secret, data = empty))
+func feishuSign(secret string, timestamp int64) string {
+	mac := hmac.New(sha256.New, []byte(strconv.FormatInt(timestamp, 10)+"\n"+secret))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// WeComNotifier WeCom robots have no sign, the key in the url is the secret
+type WeComNotifier struct{}
+
+func (WeComNotifier) Validate(target *Target) error {
+	return validateHTTPURL(target.Address)
+}
+
+func (WeComNotifier) Send(target *Target, msg *Message) error {
+	payload := map[string]any{
+		"msgtype": "markdown",
+		"markdown": map[string]string{
+			"content": botMarkdown(msg, "### "+botTitle(msg)+"\n", weComMaxBytes),
+		},
+	}
+	return postBot(target.Address, payload)
+}
+
+func init() {
+	Register(ChannelDingTalk, DingTalkNotifier{})
+	Register(ChannelFeishu, FeishuNotifier{})
+	Register(ChannelWeCom, WeComNotifier{})
+}
@@ -0,0 +1,170 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestDingTalkSign(t *testing.T) {
+	// HmacSHA256 keyed by the secret over timestamp + "\n" + secret
+	if got, want := dingTalkSign("SEC000secret", 1700000000000), "tTfOwY6eBcGZXnIEbxsq+Nl6fWOBelRgIv5rn6hl3fg="; got != want {
+		t.Errorf("dingTalkSign = %s, want %s", got, want)
+	}
+}
+
+func TestFeishuSign(t *testing.T) {
+	// HmacSHA256 keyed by timestamp + "\n" + secret over nothing
+	if got, want := feishuSign("SEC000secret", 1700000000), "iMNSwkEXsumQFuw3+PzIr5c8iW2UGddkaXmyE4s/bgU="; got != want {
+		t.Errorf("feishuSign = %s, want %s", got, want)
+	}
+	if feishuSign("SEC000secret", 1700000000) == dingTalkSign("SEC000secret", 1700000000) {
+		t.Error("the two platforms sign the same way")
+	}
+}
+
+// longMessage a body far over every limit, the links must survive the cut
+func longMessage() *Message {
+	return &Message{
+		Title: "关于奖学金评定的通知",
+		Body:  "<p>" + strings.Repeat("评定细则。", 10000) + "</p>",
+		URL:   "https://example.com/notice/1",
+		Links: []Link{{Title: "名单.pdf", URL: "https://example.com/files/1"}},
+	}
+}
+
+func TestIMBotSend(t *testing.T) {
+	allowLocalTargets(t)
+	srv, requests := fakeServer(t, func(r *http.Request) (int, string) { return http.StatusOK, `{"errcode":0,"errmsg":"ok"}` })
+
+	cases := []struct {
+		channel string
+		n       Notifier
+		secret  string
+		limit   int
+		content func(payload map[string]any) string
+	}{
+		{ChannelDingTalk, DingTalkNotifier{}, "SEC000secret", dingTalkMaxBytes, func(p map[string]any) string {
+			return p["markdown"].(map[string]any)["text"].(string)
+		}},
+		{ChannelFeishu, FeishuNotifier{}, "SEC000secret", feishuMaxBytes, func(p map[string]any) string {
+			elements := p["card"].(map[string]any)["elements"].([]any)
+			return elements[0].(map[string]any)["text"].(map[string]any)["content"].(string)
+		}},
+		{ChannelWeCom, WeComNotifier{}, "", weComMaxBytes, func(p map[string]any) string {
+			return p["markdown"].(map[string]any)["content"].(string)
+		}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.channel, func(t *testing.T) {
+			before := len(requests())
+			target := &Target{Channel: tc.channel, Address: srv.URL + "/robot/send?access_token=abc", Secret: tc.secret}
+			if err := tc.n.Send(target, longMessage()); err != nil {
+				t.Fatal(err)
+			}
+			reqs := requests()
+			if len(reqs) != before+1 {
+				t.Fatalf("got %d requests, want 1", len(reqs)-before)
+			}
+			content := tc.content(reqs[before].JSON)
+			if len(content) > tc.limit {
+				t.Errorf("content is %d bytes, limit %d", len(content), tc.limit)
+			}
+			if !strings.Contains(content, "评定细则") ||
+				!strings.Contains(content, "[名单.pdf](https://example.com/files/1)") ||
+				!strings.Contains(content, "[查看原文](https://example.com/notice/1)") {
+				t.Errorf("content lost the body or the links: ...%s", content[max(len(content)-200, 0):])
+			}
+		})
+	}
+
+	// the sign of Feishu is in the body
+	reqs := requests()
+	feishu := reqs[1].JSON
+	ts, err := strconv.ParseInt(feishu["timestamp"].(string), 10, 64)
+	if err != nil || feishu["sign"] != feishuSign("SEC000secret", ts) {
+		t.Errorf("feishu timestamp %v sign %v", feishu["timestamp"], feishu["sign"])
+	}
+}
+
+func TestDingTalkSignedURL(t *testing.T) {
+	allowLocalTargets(t)
+	var query map[string][]string
+	srv, _ := fakeServer(t, func(r *http.Request) (int, string) {
+		query = r.URL.Query()
+		return http.StatusOK, `{"errcode":0,"errmsg":"ok"}`
+	})
+
+	target := &Target{Address: srv.URL + "/robot/send?access_token=abc", Secret: "SEC000secret"}
+	if err := (DingTalkNotifier{}).Send(target, &Message{Title: "通知"}); err != nil {
+		t.Fatal(err)
+	}
+	ts, err := strconv.ParseInt(strings.Join(query["timestamp"], ""), 10, 64)
+	if err != nil || strings.Join(query["sign"], "") != dingTalkSign("SEC000secret", ts) {
+		t.Errorf("query = %v", query)
+	}
+	if strings.Join(query["access_token"], "") != "abc" {
+		t.Errorf("access_token lost: %v", query)
+	}
+}
+
+func TestIMBotErrors(t *testing.T) {
+	allowLocalTargets(t)
+
+	cases := []struct {
+		name      string
+		status    int
+		body      string
+		permanent bool
+	}{
+		{"ok", 200, `{"errcode":0,"errmsg":"ok"}`, false},
+		{"dingtalk bad token", 200, `{"errcode":300001,"errmsg":"token is not exist"}`, true},
+		{"dingtalk sign mismatch", 200, `{"errcode":310000,"errmsg":"sign not match"}`, true},
+		{"dingtalk robot disabled", 200, `{"errcode":400102,"errmsg":"robot stopped"}`, true},
+		{"dingtalk too fast", 200, `{"errcode":130101,"errmsg":"send too fast"}`, false},
+		{"feishu sign mismatch", 200, `{"code":19021,"msg":"sign match fail or timestamp is not within one hour from current time"}`, true},
+		{"feishu keyword", 200, `{"code":19024,"msg":"Key Words Not Found"}`, true},
+		{"feishu rate limited", 200, `{"code":9499,"msg":"too many request"}`, false},
+		{"wecom removed key", 200, `{"errcode":93000,"errmsg":"invalid webhook url"}`, true},
+		{"wecom rate limited", 200, `{"errcode":45009,"errmsg":"api freq out of limit"}`, false},
+		{"not found", 404, `not found`, true},
+		{"bad request", 400, `{"code":19002,"msg":"params error"}`, true},
+		{"throttled", 429, ``, false},
+		{"down", 502, `bad gateway`, false},
+		{"not json", 200, `<html></html>`, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv, _ := fakeServer(t, func(r *http.Request) (int, string) { return tc.status, tc.body })
+
+			err := postBot(srv.URL, map[string]string{"msgtype": "text"})
+			if tc.name == "ok" {
+				if err != nil {
+					t.Errorf("postBot = %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("postBot succeeded")
+			}
+			if IsPermanent(err) != tc.permanent {
+				t.Errorf("IsPermanent(%v) = %v, want %v", err, IsPermanent(err), tc.permanent)
+			}
+		})
+	}
+}
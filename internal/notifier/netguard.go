@@ -0,0 +1,94 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+
+	"noticat/pkg/global"
+)
+
+// targets are urls given by users, the server must not be used to reach the
+// services next to it (redis, cloud metadata, the LAN)
+
+func allowPrivateTargets() bool {
+	return strings.EqualFold(global.AllowPrivateTargets, "true")
+}
+
+// isPublicIP not loopback, private, link-local, multicast or unspecified
+func isPublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() || ip.IsUnspecified())
+}
+
+// checkPublicHost every address host resolves to must be public
+func checkPublicHost(host string) error {
+	if allowPrivateTargets() {
+		return nil
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		if !isPublicIP(ip) {
+			return fmt.Errorf("不允许使用内网地址: %s", host)
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("无法解析域名: %s", host)
+	}
+	for _, addr := range addrs {
+		if !isPublicIP(addr.IP) {
+			return fmt.Errorf("不允许使用内网地址: %s (%s)", host, addr.IP)
+		}
+	}
+	return nil
+}
+
+// guardDial refuse to connect to a non public address, checked on the address
+// actually dialed so a host re-resolving to one (DNS rebinding) is caught too
+func guardDial(network, address string, _ syscall.RawConn) error {
+	if allowPrivateTargets() {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+		return Permanent(fmt.Errorf("不允许连接内网地址: %s", host))
+	}
+	return nil
+}
+
+// newTargetHTTPClient a client for the urls of targets, no proxy so the dial
+// guard sees the real destination
+func newTargetHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: guardDial}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
@@ -0,0 +1,142 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package notifier delivery channels
+package notifier
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Target where a message goes, Address meaning depends on the channel
+// (email address, webhook url, chat id...)
+type Target struct {
+	// ID of model.NotifyTarget, 0 for the account email
+	ID      uint
+	Channel string
+	Address string
+	Secret  string
+}
+
+type Link struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+type Message struct {
+	Subject     string
+	Body        string
+	Attachments []string
+
+	// the notice behind this message, empty for system mails (register code...)
+	Title          string
+	URL            string
+	Date           string
+	Client         string
+	SubscriptionID uint
+	Links          []Link
+	// one-click unsubscribe of the subscription, mails carry it as List-Unsubscribe
+	UnsubscribeURL string
+	// "updated" for an edited notice, empty: a new one
+	Event string
+	// set by the delivery queue, the same for every attempt of a job
+	DeliveryID string
+}
+
+// Notifier a delivery channel
+type Notifier interface {
+	Send(target *Target, msg *Message) error
+}
+
+// Validator optional, check a target before it is saved
+type Validator interface {
+	Validate(target *Target) error
+}
+
+var (
+	mu       sync.RWMutex
+	registry = make(map[string]Notifier)
+)
+
+// Register add a channel implementation, the later one replaces the former
+func Register(channel string, n Notifier) {
+	mu.Lock()
+	defer mu.Unlock()
+	registry[channel] = n
+}
+
+func Get(channel string) (Notifier, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	n, ok := registry[channel]
+	return n, ok
+}
+
+func Channels() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	channels := make([]string, 0, len(registry))
+	for name := range registry {
+		channels = append(channels, name)
+	}
+	return channels
+}
+
+// PermanentError retrying will not help (bad target, rejected recipient...)
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{Err: err}
+}
+
+func IsPermanent(err error) bool {
+	var p *PermanentError
+	return errors.As(err, &p)
+}
+
+// Validate check target with its channel
+func Validate(target *Target) error {
+	n, ok := Get(target.Channel)
+	if !ok {
+		return fmt.Errorf("未注册的通知渠道: %s", target.Channel)
+	}
+	if v, ok := n.(Validator); ok {
+		return v.Validate(target)
+	}
+	return nil
+}
+
+// Send find the channel of target and send msg through it
+func Send(target *Target, msg *Message) error {
+	n, ok := Get(target.Channel)
+	if !ok {
+		return Permanent(fmt.Errorf("未注册的通知渠道: %s", target.Channel))
+	}
+	return n.Send(target, msg)
+}
@@ -0,0 +1,165 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const ChannelWebhook = "webhook"
+
+const (
+	HeaderSignature = "X-NotiCat-Signature"
+	HeaderTimestamp = "X-NotiCat-Timestamp"
+	HeaderDelivery  = "X-NotiCat-Delivery"
+)
+
+type WebhookPayload struct {
+	Event          string `json:"event"`
+	Title          string `json:"title"`
+	URL            string `json:"url"`
+	Date           string `json:"date"`
+	Client         string `json:"client"`
+	SubscriptionID uint   `json:"subscription_id"`
+	Body           string `json:"body"`
+	Attachments    []Link `json:"attachments"`
+	SentAt         int64  `json:"sent_at"`
+}
+
+// WebhookNotifier POST the notice as json to target.Address.
+//
+// Signature = "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + body)),
+// receivers should reject timestamps too far from now to stop replays.
+// One attempt per Send, the delivery queue retries the errors that are not Permanent.
+type WebhookNotifier struct {
+	Client *http.Client
+}
+
+func NewWebhookNotifier() *WebhookNotifier {
+	return &WebhookNotifier{
+		Client: newTargetHTTPClient(10 * time.Second),
+	}
+}
+
+// Sign compute the signature header value
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (w *WebhookNotifier) Validate(target *Target) error {
+	return validateHTTPURL(target.Address)
+}
+
+func (w *WebhookNotifier) Send(target *Target, msg *Message) error {
+	links := msg.Links
+	if links == nil {
+		links = []Link{}
+	}
+
+	event := msg.Event
+	if event == "" {
+		event = "notice"
+	}
+
+	body, err := json.Marshal(WebhookPayload{
+		Event:          event,
+		Title:          msg.Title,
+		URL:            msg.URL,
+		Date:           msg.Date,
+		Client:         msg.Client,
+		SubscriptionID: msg.SubscriptionID,
+		Body:           msg.Body,
+		Attachments:    links,
+		SentAt:         time.Now().Unix(),
+	})
+	if err != nil {
+		return fmt.Errorf("webhook payload 序列化失败: %w", err)
+	}
+
+	// the same delivery id for every retry of the queue, receivers can dedupe with it
+	deliveryID := msg.DeliveryID
+	if deliveryID == "" {
+		deliveryID = uuid.New().String()
+	}
+	return w.post(target, deliveryID, body)
+}
+
+// post one attempt, 4xx (but 429) is Permanent
+func (w *WebhookNotifier) post(target *Target, deliveryID string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, target.Address, bytes.NewReader(body))
+	if err != nil {
+		return Permanent(err)
+	}
+
+	// sign every attempt with a fresh timestamp
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "NotiCat-Webhook")
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderDelivery, deliveryID)
+	if target.Secret != "" {
+		req.Header.Set(HeaderSignature, Sign(target.Secret, timestamp, body))
+	}
+
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	return statusError(resp.StatusCode, fmt.Errorf("webhook 返回状态码 %d", resp.StatusCode))
+}
+
+// statusError err of a failed response, 4xx (but 429) is Permanent:
+// the url or the credentials are wrong, retrying will not help
+func statusError(code int, err error) error {
+	if code >= 400 && code < 500 && code != http.StatusTooManyRequests {
+		return Permanent(err)
+	}
+	return err
+}
+
+func validateHTTPURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("无效的 URL: %s", raw)
+	}
+	return checkPublicHost(u.Hostname())
+}
+
+func init() {
+	Register(ChannelWebhook, NewWebhookNotifier())
+}
@@ -29,21 +29,18 @@ import (
 	"noticat/pkg/global"
 )
 
-var shanghaiLoc *time.Location
+var schedulerLoc *time.Location
 
 func StartScheduler() {
-	var err error
-	shanghaiLoc, err = time.LoadLocation("Asia/Shanghai")
-	if err != nil {
-		log.Fatalf("无法加载时区: %v", err)
-	}
+	// an invalid NOTICAT_TIMEZONE falls back to local time, like everywhere else
+	schedulerLoc = service.ServerLocation()
 
 	c := cron.New(
-		cron.WithLocation(shanghaiLoc),
+		cron.WithLocation(schedulerLoc),
 		cron.WithChain(cron.Recover(cron.DefaultLogger)),
 	)
 
-	_, err = c.AddFunc("@every 30m", func() {
+	_, err := c.AddFunc("@every 30m", func() {
 		log.Println("[Scheduler] 🔔 触发整点扫描，开始派发任务...")
 		DispatchAllTasks()
 	})
@@ -51,6 +48,24 @@ func StartScheduler() {
 		log.Println("[Scheduler] 任务派发过程出现异常，跳过异常")
 	}
 
+	// a slow run (details fetched for the released notices) must not overlap the next one
+	minutely := cron.NewChain(cron.SkipIfStillRunning(cron.DefaultLogger)).Then(cron.FuncJob(func() {
+		now := time.Now().In(schedulerLoc)
+		service.FlushDigests(now)
+		service.ReleaseQuietNotices(now)
+	}))
+	_, err = c.AddJob("* * * * *", minutely)
+	if err != nil {
+		log.Println("[Scheduler] 摘要/免打扰任务注册失败，跳过异常")
+	}
+
+	_, err = c.AddFunc("@every 1h", func() {
+		service.CollectStoredFiles(time.Now())
+	})
+	if err != nil {
+		log.Println("[Scheduler] 附件清理任务注册失败，跳过异常")
+	}
+
 	c.Start()
 	log.Println("[Scheduler] 🚀 调度服务已上线，运行频率：每30分钟/次")
 }
@@ -62,7 +77,7 @@ func DispatchAllTasks() {
 		return
 	}
 
-	now := time.Now().In(shanghaiLoc)
+	now := time.Now().In(schedulerLoc)
 	log.Printf("[Scheduler] ⏰ Cron 触发 | time=%s | unix=%d", now.Format("2006-01-02 15:04:05"), now.Unix())
 	log.Printf("[Scheduler] 本次共发现 %d 个待执行任务", len(tasks))
 
@@ -0,0 +1,91 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package search full-text index of the notice archive, sqlite fts5 when the
+// driver is built with it (-tags sqlite_fts5), LIKE otherwise
+package search
+
+import (
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// Table the fts5 table, rowid is the id of the notice
+const Table = "notice_fts"
+
+var enabled bool
+
+// Enabled fts5 is available and the index is in use
+func Enabled() bool {
+	return enabled
+}
+
+// Init create the index, and fill it when it is new
+func Init(db *gorm.DB) {
+	err := db.Exec("CREATE VIRTUAL TABLE IF NOT EXISTS " + Table + " USING fts5(title, body, tokenize = 'unicode61')").Error
+	if err != nil {
+		log.Printf("[Search] FTS5 不可用，全文搜索退化为 LIKE: %v", err)
+		return
+	}
+	enabled = true
+
+	var indexed int64
+	if err := db.Raw("SELECT count(*) FROM " + Table).Scan(&indexed).Error; err != nil || indexed > 0 {
+		return
+	}
+
+	var ids []uint
+	if err := db.Table("notices").Where("deleted_at IS NULL").Pluck("id", &ids).Error; err != nil {
+		log.Printf("[Search] 建立索引失败: %v", err)
+		return
+	}
+	for _, id := range ids {
+		if err := Index(db, id); err != nil {
+			log.Printf("[Search] 建立索引失败: %v", err)
+			return
+		}
+	}
+	if len(ids) > 0 {
+		log.Printf("[Search] 已为 %d 条通知建立索引", len(ids))
+	}
+}
+
+// Index (re)index the notice with the given id from the notices table
+func Index(db *gorm.DB, id uint) error {
+	if !enabled || id == 0 {
+		return nil
+	}
+
+	var row struct {
+		Title string
+		Body  string
+	}
+	if err := db.Raw("SELECT title, body FROM notices WHERE id = ?", id).Scan(&row).Error; err != nil {
+		return err
+	}
+	if err := Remove(db, id); err != nil {
+		return err
+	}
+	return db.Exec("INSERT INTO "+Table+" (rowid, title, body) VALUES (?, ?, ?)",
+		id, Tokenize(row.Title), Tokenize(PlainText(row.Body))).Error
+}
+
+// Remove drop the notice from the index
+func Remove(db *gorm.DB, id uint) error {
+	if !enabled || id == 0 {
+		return nil
+	}
+	return db.Exec("DELETE FROM "+Table+" WHERE rowid = ?", id).Error
+}
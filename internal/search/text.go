@@ -0,0 +1,188 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// cjk scripts have no spaces between words, they are indexed as bigrams
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+func isWord(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// segment a run of word runes, cjk or not
+type segment struct {
+	runes []rune
+	cjk   bool
+}
+
+func segments(s string) []segment {
+	var segs []segment
+	var cur []rune
+	curCJK := false
+	flush := func() {
+		if len(cur) > 0 {
+			segs = append(segs, segment{runes: cur, cjk: curCJK})
+			cur = nil
+		}
+	}
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case isCJK(r):
+			if !curCJK {
+				flush()
+			}
+			curCJK = true
+			cur = append(cur, r)
+		case isWord(r):
+			if curCJK {
+				flush()
+			}
+			curCJK = false
+			cur = append(cur, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return segs
+}
+
+func bigrams(runes []rune) []string {
+	grams := make([]string, 0, len(runes))
+	for i := 0; i+1 < len(runes); i++ {
+		grams = append(grams, string(runes[i:i+2]))
+	}
+	return grams
+}
+
+// Tokenize text -> the indexed form, words as is and cjk as bigrams followed by
+// the last unigram ("奖学金" -> "奖学 学金 金"), so every character starts a token
+func Tokenize(s string) string {
+	var tokens []string
+	for _, seg := range segments(s) {
+		if seg.cjk {
+			tokens = append(tokens, bigrams(seg.runes)...)
+			tokens = append(tokens, string(seg.runes[len(seg.runes)-1]))
+			continue
+		}
+		tokens = append(tokens, string(seg.runes))
+	}
+	return strings.Join(tokens, " ")
+}
+
+// Terms the words of a user query, used for LIKE and highlighting
+func Terms(q string) []string {
+	terms := strings.Fields(q)
+	if len(terms) > 10 {
+		terms = terms[:10]
+	}
+	return terms
+}
+
+// MatchQuery user query -> fts5 MATCH expression, every term must match.
+// a cjk run is a phrase of its bigrams, a single character or a word is a prefix
+func MatchQuery(q string) string {
+	var parts []string
+	for _, term := range Terms(q) {
+		for _, seg := range segments(term) {
+			if seg.cjk && len(seg.runes) > 1 {
+				parts = append(parts, `"`+strings.Join(bigrams(seg.runes), " ")+`"`)
+				continue
+			}
+			parts = append(parts, `"`+string(seg.runes)+`"*`)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+var (
+	reBlock = regexp.MustCompile(`(?is)<(script|style)[^>]*>.*?</(script|style)>`)
+	reTag   = regexp.MustCompile(`(?s)<[^>]*>`)
+)
+
+// PlainText detail html -> text with collapsed whitespace
+func PlainText(s string) string {
+	s = reBlock.ReplaceAllString(s, " ")
+	s = reTag.ReplaceAllString(s, " ")
+	return strings.Join(strings.Fields(html.UnescapeString(s)), " ")
+}
+
+// Snippet about width runes of text around the first hit, html escaped with the
+// hits in <mark>. width <= 0: the whole text
+func Snippet(text string, terms []string, width int) string {
+	runes := []rune(text)
+	lower := []rune(strings.ToLower(text))
+	if len(lower) != len(runes) {
+		lower = runes
+	}
+
+	// marked[i]: runes[i] is part of a hit
+	marked := make([]bool, len(runes))
+	first := -1
+	for _, term := range terms {
+		t := []rune(strings.ToLower(term))
+		if len(t) == 0 {
+			continue
+		}
+		for i := 0; i+len(t) <= len(lower); i++ {
+			if string(lower[i:i+len(t)]) != string(t) {
+				continue
+			}
+			for j := i; j < i+len(t); j++ {
+				marked[j] = true
+			}
+			if first < 0 || i < first {
+				first = i
+			}
+		}
+	}
+
+	start, end := 0, len(runes)
+	if width > 0 && len(runes) > width {
+		start = max(first-width/4, 0)
+		end = min(start+width, len(runes))
+		start = max(end-width, 0)
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	for i := start; i < end; {
+		j := i
+		for j < end && marked[j] == marked[i] {
+			j++
+		}
+		part := html.EscapeString(string(runes[i:j]))
+		if marked[i] {
+			part = "<mark>" + part + "</mark>"
+		}
+		b.WriteString(part)
+		i = j
+	}
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
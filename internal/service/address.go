@@ -0,0 +1,132 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"errors"
+	"log"
+
+	"gorm.io/gorm"
+
+	"noticat/internal/model"
+	"noticat/pkg/global"
+)
+
+var ErrAddressNotFound = errors.New("邮箱地址不存在")
+
+// subscriptionEmails where the mails of each subscription go,
+// the account email when a subscription has no route
+func subscriptionEmails(user *model.User, subIDs []uint) map[uint][]string {
+	emails := make(map[uint][]string, len(subIDs))
+	for _, id := range subIDs {
+		emails[id] = nil
+	}
+
+	var routes []model.SubscriptionAddress
+	if err := global.DB.Where("subscription_id IN ?", subIDs).Order("address_id").Find(&routes).Error; err != nil {
+		log.Printf("查询订阅地址失败: %v", err)
+		routes = nil
+	}
+
+	var addresses []model.UserAddress
+	if len(routes) > 0 {
+		if err := global.DB.Where("user_id = ?", user.ID).Find(&addresses).Error; err != nil {
+			log.Printf("查询用户 %d 的邮箱地址失败: %v", user.ID, err)
+		}
+	}
+	byID := map[uint]string{0: user.Email}
+	for _, a := range addresses {
+		byID[a.ID] = a.Email
+	}
+
+	for _, r := range routes {
+		if email, ok := byID[r.AddressID]; ok {
+			emails[r.SubscriptionID] = append(emails[r.SubscriptionID], email)
+		}
+	}
+	for id, list := range emails {
+		if len(list) == 0 {
+			emails[id] = []string{user.Email}
+		}
+	}
+	return emails
+}
+
+// SubscriptionAddressIDs the routes of a subscription, empty: account email only
+func SubscriptionAddressIDs(subscriptionID uint) ([]uint, error) {
+	ids := []uint{}
+	err := global.DB.Model(&model.SubscriptionAddress{}).
+		Where("subscription_id = ?", subscriptionID).
+		Order("address_id").
+		Pluck("address_id", &ids).Error
+	return ids, err
+}
+
+// SetSubscriptionAddresses route a subscription to the addresses, 0 is the
+// account email. an empty list goes back to the account email
+func SetSubscriptionAddresses(userID uint, subscriptionID uint, addressIDs []uint) error {
+	return global.DB.Transaction(func(tx *gorm.DB) error {
+		var sub model.UserSubscription
+		if err := tx.Where("id = ? AND user_id = ?", subscriptionID, userID).First(&sub).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrSubscriptionNotFound
+			}
+			return err
+		}
+
+		seen := make(map[uint]bool)
+		var routes []model.SubscriptionAddress
+		for _, id := range addressIDs {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+
+			if id != 0 {
+				var count int64
+				if err := tx.Model(&model.UserAddress{}).Where("id = ? AND user_id = ?", id, userID).Count(&count).Error; err != nil {
+					return err
+				}
+				if count == 0 {
+					return ErrAddressNotFound
+				}
+			}
+			routes = append(routes, model.SubscriptionAddress{SubscriptionID: sub.ID, AddressID: id})
+		}
+
+		if err := tx.Where("subscription_id = ?", sub.ID).Delete(&model.SubscriptionAddress{}).Error; err != nil {
+			return err
+		}
+		if len(routes) == 0 {
+			return nil
+		}
+		return tx.Create(&routes).Error
+	})
+}
+
+// DeleteAddress remove an address and its routes, the subscriptions left
+// without a route fall back to the account email
+func DeleteAddress(userID uint, addressID uint) error {
+	return global.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Where("id = ? AND user_id = ?", addressID, userID).Delete(&model.UserAddress{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrAddressNotFound
+		}
+		return tx.Where("address_id = ?", addressID).Delete(&model.SubscriptionAddress{}).Error
+	})
+}
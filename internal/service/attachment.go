@@ -0,0 +1,216 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"archive/zip"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"noticat/internal/model"
+	"noticat/pkg/global"
+)
+
+const (
+	AttachZipOff    = "off"
+	AttachZipAuto   = "auto"
+	AttachZipAlways = "always"
+)
+
+type AttachmentPolicy struct {
+	// bytes
+	MaxFile     int64
+	MaxTotal    int64
+	Zip         string
+	ZipMinFiles int
+}
+
+func atoiOr(s string, fallback int) int {
+	n, err := strconv.Atoi(s)
+	if err != nil || n <= 0 {
+		return fallback
+	}
+	return n
+}
+
+// AttachmentLimitsMB the server limits, users may only go below them since
+// they keep the mails within the size caps of the providers
+func AttachmentLimitsMB() (maxFile int, maxTotal int) {
+	return atoiOr(global.AttachMaxFileMB, 15), atoiOr(global.AttachMaxTotalMB, 20)
+}
+
+// attachmentPolicy the server policy with the overrides of the user
+func attachmentPolicy(user *model.User) AttachmentPolicy {
+	maxFile, maxTotal := AttachmentLimitsMB()
+	zipMode := global.AttachZip
+
+	if user != nil {
+		if user.AttachMaxFileMB > 0 {
+			maxFile = min(user.AttachMaxFileMB, maxFile)
+		}
+		if user.AttachMaxTotalMB > 0 {
+			maxTotal = min(user.AttachMaxTotalMB, maxTotal)
+		}
+		if user.AttachZip != "" {
+			zipMode = user.AttachZip
+		}
+	}
+
+	return AttachmentPolicy{
+		MaxFile:     int64(maxFile) << 20,
+		MaxTotal:    int64(maxTotal) << 20,
+		Zip:         zipMode,
+		ZipMinFiles: atoiOr(global.AttachZipMinFiles, 4),
+	}
+}
+
+func attachLinkTTL() time.Duration {
+	return time.Duration(atoiOr(global.AttachLinkTTLHours, 168)) * time.Hour
+}
+
+// storedLink an oversized attachment replaced by a download link
+type storedLink struct {
+	Name string
+	URL  string
+	Size int64
+}
+
+// spooledFile a downloaded attachment in the spool, Hash is its blob in the file store
+type spooledFile struct {
+	Path string
+	Hash string
+	Size int64
+}
+
+// applyAttachmentPolicy decide what is attached. files over the limits come
+// back as links to the file store
+func applyAttachmentPolicy(policy AttachmentPolicy, files []spooledFile) ([]string, []storedLink) {
+	if len(files) == 0 {
+		return nil, nil
+	}
+
+	// one bundle when there are many files, fall back if it is too big
+	zipWanted := policy.Zip == AttachZipAlways || (policy.Zip == AttachZipAuto && len(files) >= policy.ZipMinFiles)
+	if zipWanted && len(files) > 1 {
+		paths := make([]string, 0, len(files))
+		for _, f := range files {
+			paths = append(paths, f.Path)
+		}
+		bundle, size, err := zipFiles(filepath.Dir(files[0].Path), paths)
+		if err != nil {
+			log.Printf("打包附件失败: %v", err)
+		}
+		if err == nil && size <= policy.MaxFile && size <= policy.MaxTotal {
+			return []string{bundle}, nil
+		}
+		if bundle != "" {
+			os.Remove(bundle)
+		}
+	}
+
+	var attach []string
+	var links []storedLink
+	var total int64
+	for _, f := range files {
+		if f.Size <= policy.MaxFile && total+f.Size <= policy.MaxTotal {
+			attach = append(attach, f.Path)
+			total += f.Size
+			continue
+		}
+
+		name := filepath.Base(f.Path)
+		if f.Hash == "" {
+			log.Printf("附件 %s 超限且未能保存，已跳过", name)
+			continue
+		}
+		url, err := FileURL(f.Hash, name, attachLinkTTL())
+		if err != nil {
+			log.Printf("生成附件链接失败: %v", err)
+			continue
+		}
+		links = append(links, storedLink{Name: name, URL: url, Size: f.Size})
+	}
+	return attach, links
+}
+
+// zipFiles bundle files into a new zip in dir, its name never clashes with
+// one of the files
+func zipFiles(dir string, files []string) (string, int64, error) {
+	out, err := os.CreateTemp(dir, "attachments-*.zip")
+	if err != nil {
+		return "", 0, err
+	}
+	defer out.Close()
+
+	zw := zip.NewWriter(out)
+	for _, f := range files {
+		if err := addToZip(zw, f); err != nil {
+			zw.Close()
+			return out.Name(), 0, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return out.Name(), 0, err
+	}
+
+	info, err := out.Stat()
+	if err != nil {
+		return out.Name(), 0, err
+	}
+	return out.Name(), info.Size(), nil
+}
+
+func addToZip(zw *zip.Writer, path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	w, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     filepath.Base(path),
+		Method:   zip.Deflate,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, in)
+	return err
+}
+
+// renderStoredLinks the html appended to the body for oversized attachments
+func renderStoredLinks(links []storedLink) string {
+	if len(links) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	expire := time.Now().Add(attachLinkTTL()).In(ServerLocation()).Format("2006-01-02 15:04")
+	sb.WriteString(fmt.Sprintf("<p>以下附件超过大小限制，请在 %s 前下载：</p><ul>", expire))
+	for _, l := range links {
+		sb.WriteString(fmt.Sprintf(`<li><a href="%s">%s</a> (%.1f MB)</li>`,
+			html.EscapeString(l.URL), html.EscapeString(l.Name), float64(l.Size)/(1<<20)))
+	}
+	sb.WriteString("</ul>")
+	return sb.String()
+}
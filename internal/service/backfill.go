@@ -0,0 +1,148 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"errors"
+	"log"
+	"slices"
+	"time"
+
+	"noticat/internal/bridge"
+	"noticat/internal/model"
+	"noticat/pkg/common"
+	"noticat/pkg/global"
+)
+
+// backfill modes of a new subscription
+const (
+	BackfillNone   = "none"
+	BackfillLatest = "latest"
+	BackfillSince  = "since"
+)
+
+// BackfillLimit the most notices a backfill sends
+const BackfillLimit = 50
+
+var ErrBackfillInvalid = errors.New("回填参数错误")
+
+// Backfill which of the listed notices a new subscription gets right away
+type Backfill struct {
+	Mode  string
+	Count int
+	Since time.Time
+}
+
+// ParseBackfill check the options, since is a date ParseDate understands
+func ParseBackfill(mode string, count int, since string) (*Backfill, error) {
+	b := &Backfill{Mode: mode}
+	switch mode {
+	case "", BackfillNone:
+		b.Mode = BackfillNone
+	case BackfillLatest:
+		if count <= 0 || count > BackfillLimit {
+			return nil, ErrBackfillInvalid
+		}
+		b.Count = count
+	case BackfillSince:
+		t, _, ok := common.ParseDate(since, ServerLocation())
+		if !ok {
+			return nil, ErrBackfillInvalid
+		}
+		b.Since = t
+	default:
+		return nil, ErrBackfillInvalid
+	}
+	return b, nil
+}
+
+// Select the notices to backfill, newest first. latest: the Count most recently
+// published, the ones without a date count as older than the dated ones and keep
+// the order of the list. since: the ones published since Since, undated ones are
+// left out. at most BackfillLimit either way
+func (b *Backfill) Select(notices []bridge.Notice) []bridge.Notice {
+	if b == nil || b.Mode == BackfillNone {
+		return nil
+	}
+
+	picked := make([]bridge.Notice, 0, len(notices))
+	for _, n := range notices {
+		if n.PublishedAt == nil {
+			if t, _, ok := common.ParseDate(n.Date, ServerLocation()); ok {
+				n.PublishedAt = &t
+			}
+		}
+		if b.Mode == BackfillSince && (n.PublishedAt == nil || n.PublishedAt.Before(b.Since)) {
+			continue
+		}
+		picked = append(picked, n)
+	}
+	slices.SortStableFunc(picked, func(x, y bridge.Notice) int {
+		switch {
+		case x.PublishedAt == nil && y.PublishedAt == nil:
+			return 0
+		case x.PublishedAt == nil:
+			return 1
+		case y.PublishedAt == nil:
+			return -1
+		}
+		return y.PublishedAt.Compare(*x.PublishedAt)
+	})
+
+	limit := BackfillLimit
+	if b.Mode == BackfillLatest {
+		limit = b.Count
+	}
+	if len(picked) > limit {
+		picked = picked[:limit]
+	}
+	return picked
+}
+
+// DispatchBackfill send the backfilled notices to the new subscription through
+// the usual filter and delivery stages, oldest first. it runs on its own
+// goroutine, a panic is logged instead of taking the server down
+func DispatchBackfill(subID uint, fetchCtx *FetchContext, notices []bridge.Notice) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[Panic] 订阅 %d 回填时崩溃: %v", subID, r)
+		}
+	}()
+
+	if len(notices) == 0 {
+		return
+	}
+
+	var sub model.UserSubscription
+	err := global.DB.Preload("Filters").Preload("User").First(&sub, subID).Error
+	if err != nil {
+		log.Printf("[Backfill] 读取订阅 %d 失败: %v", subID, err)
+		return
+	}
+
+	rep := newPipelineReport()
+	defer rep.log(sub.TaskID)
+
+	sortByPublished(notices, ServerLocation())
+	batch := &Batch{
+		TaskID:      sub.TaskID,
+		Ctx:         fetchCtx,
+		Notices:     notices,
+		Subscribers: []*Subscriber{newSubscriber(&sub)},
+	}
+	if err := dispatchBatch(batch, rep); err != nil {
+		log.Printf("[Backfill] 订阅 %d 回填失败: %v", subID, err)
+	}
+}
@@ -0,0 +1,298 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"noticat/internal/bridge"
+	"noticat/internal/filestore"
+	"noticat/pkg/common"
+	"noticat/pkg/global"
+)
+
+const (
+	contentCacheSize = 128
+	contentCacheTTL  = 10 * time.Minute
+)
+
+// noticeContent the detail of a notice with its attachments in the file store,
+// fetched once and shared by every subscriber
+type noticeContent struct {
+	Body        string
+	Attachments []bridge.Attachment
+	// same order as Attachments, Hash == "": the download failed
+	Files []contentFile
+}
+
+type contentFile struct {
+	Name string
+	Hash string
+	Size int64
+	// the download when it could not be stored, removed after unstoredTTL
+	Path string
+}
+
+// how long a download the file store refused stays around for the spools
+const unstoredTTL = 2 * contentCacheTTL
+
+type contentEntry struct {
+	key     string
+	content *noticeContent
+	expires time.Time
+}
+
+// contentCache a small LRU of fetched contents, so concurrent tasks and the
+// quiet hours release share the work for the same url
+type contentCache struct {
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+	group singleflight.Group
+}
+
+var noticeContents = &contentCache{
+	ll:    list.New(),
+	items: make(map[string]*list.Element),
+}
+
+func (c *contentCache) get(key string, now time.Time) (*noticeContent, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*contentEntry)
+	if now.After(entry.expires) {
+		c.ll.Remove(el)
+		delete(c.items, key)
+		return nil, false
+	}
+	c.ll.MoveToFront(el)
+	return entry.content, true
+}
+
+func (c *contentCache) add(key string, content *noticeContent, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.ll.Remove(el)
+	}
+	c.items[key] = c.ll.PushFront(&contentEntry{key: key, content: content, expires: now.Add(contentCacheTTL)})
+	for c.ll.Len() > contentCacheSize {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*contentEntry).key)
+	}
+}
+
+// contentKey the same page seen with another account (or extra) may differ,
+// e.g. per-user notices, so they are part of the key
+func contentKey(fetchCtx *FetchContext, url string) string {
+	login := sha256.Sum256([]byte(fetchCtx.Account + "\n" + common.NormalizeJSON(fetchCtx.Extra)))
+	return fetchCtx.Client + "\n" + hex.EncodeToString(login[:]) + "\n" + url
+}
+
+// fetchNoticeContent the content of notice, from the cache or fetched once
+// for all callers asking at the same time. errors are not cached
+func fetchNoticeContent(fetchCtx *FetchContext, notice bridge.Notice) (*noticeContent, error) {
+	key := contentKey(fetchCtx, notice.URL)
+	if content, ok := noticeContents.get(key, time.Now()); ok {
+		return content, nil
+	}
+	return loadSharedContent(key, fetchCtx, notice)
+}
+
+// refreshNoticeContent fetch notice again whatever the cache holds, the copy
+// there may be the one an edit is to be found against
+func refreshNoticeContent(fetchCtx *FetchContext, notice bridge.Notice) (*noticeContent, error) {
+	return loadSharedContent(contentKey(fetchCtx, notice.URL), fetchCtx, notice)
+}
+
+// loadSharedContent load the content once for the callers of key and cache it
+func loadSharedContent(key string, fetchCtx *FetchContext, notice bridge.Notice) (*noticeContent, error) {
+	v, err, _ := noticeContents.group.Do(key, func() (any, error) {
+		content, err := loadNoticeContent(fetchCtx, notice)
+		if err != nil {
+			return nil, err
+		}
+		noticeContents.add(key, content, time.Now())
+		return content, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*noticeContent), nil
+}
+
+// loadNoticeContent fetch the detail and put the attachments into the file store,
+// an attachment downloaded before is not downloaded again
+func loadNoticeContent(fetchCtx *FetchContext, notice bridge.Notice) (*noticeContent, error) {
+	detail, err := bridge.FetchDetailFromPython(&bridge.DetailOptions{
+		Client:   bridge.Client(fetchCtx.Client),
+		Account:  fetchCtx.Account,
+		Password: fetchCtx.Password,
+		URL:      notice.URL,
+		Extra:    fetchCtx.Extra,
+	})
+	if err != nil {
+		return nil, err
+	}
+	saved, previous, edited := saveNoticeDetail(fetchCtx.Client, notice, detail)
+
+	content := &noticeContent{
+		Body:        detail.Body,
+		Attachments: detail.Attachments,
+		Files:       make([]contentFile, len(detail.Attachments)),
+	}
+	if edited {
+		defer notifyNoticeUpdate(fetchCtx.Client, saved, previous, content)
+	}
+	if len(detail.Attachments) == 0 {
+		return content, nil
+	}
+
+	// downloads land here before they move into the file store
+	cacheRoot := filepath.Join(".cache", "spool")
+	if err := os.MkdirAll(cacheRoot, 0o755); err != nil {
+		log.Printf("创建cache失败，下载失败: %v", err)
+		return content, nil
+	}
+	tmpDir, err := os.MkdirTemp(cacheRoot, "fetch_")
+	if err != nil {
+		log.Printf("创建临时目录失败: %v", err)
+		return content, nil
+	}
+	keep := false
+	defer func() {
+		if !keep {
+			os.RemoveAll(tmpDir)
+			return
+		}
+		time.AfterFunc(unstoredTTL, func() { os.RemoveAll(tmpDir) })
+	}()
+
+	limit := atoiOr(global.AttachDownloadMaxMB, 100)
+	noticeHash := notice.ContentHash()
+	for i, attachment := range detail.Attachments {
+		file := &content.Files[i]
+		file.Name = common.CleanFileName(attachment.Title)
+
+		if stored, size, ok := storedAttachment(fetchCtx.Client, noticeHash, attachment.URL); ok {
+			file.Hash, file.Size = stored.FileHash, size
+			continue
+		}
+
+		savePath := filepath.Join(tmpDir, file.Name)
+		err := bridge.DownloadFromPython(&bridge.DownloadOptions{
+			Client:   bridge.Client(fetchCtx.Client),
+			Account:  fetchCtx.Account,
+			Password: fetchCtx.Password,
+			URL:      attachment.URL,
+			MaxSize:  limit,
+			SavePath: savePath,
+			Referer:  notice.URL,
+			Extra:    fetchCtx.Extra,
+		})
+		if err != nil {
+			continue
+		}
+
+		hash, size, err := storeAttachment(fetchCtx.Client, noticeHash, attachment.URL, savePath)
+		if err != nil {
+			// still sent from the download, just without a stored link
+			log.Printf("保存附件到文件存储失败: %v", err)
+			if info, err := os.Stat(savePath); err == nil {
+				file.Path, file.Size = savePath, info.Size()
+				keep = true
+			}
+			continue
+		}
+		file.Hash, file.Size = hash, size
+	}
+	return content, nil
+}
+
+// spoolContent copy the stored attachments of content into a new spool dir,
+// the copies belong to one delivery and are removed with its jobs
+func spoolContent(content *noticeContent) (string, []spooledFile, []string) {
+	var hints []string
+	for i, f := range content.Files {
+		if f.Hash == "" && f.Path == "" {
+			hints = append(hints, "缺失附件: "+content.Attachments[i].Title)
+		}
+	}
+	if len(hints) == len(content.Files) {
+		return "", nil, hints
+	}
+
+	spoolDir, err := os.MkdirTemp(filepath.Join(".cache", "spool"), "noticat_")
+	if err != nil {
+		log.Printf("创建临时目录失败: %v", err)
+		return "", nil, hints
+	}
+
+	var files []spooledFile
+	for i, f := range content.Files {
+		if f.Hash == "" && f.Path == "" {
+			continue
+		}
+		path := filepath.Join(spoolDir, f.Name)
+		var err error
+		if f.Hash != "" {
+			err = filestore.CopyOut(f.Hash, path)
+		} else {
+			err = copyFile(f.Path, path)
+		}
+		if err != nil {
+			log.Printf("取出附件失败: %v", err)
+			hints = append(hints, "缺失附件: "+content.Attachments[i].Title)
+			continue
+		}
+		files = append(files, spooledFile{Path: path, Hash: f.Hash, Size: f.Size})
+	}
+	return spoolDir, files, hints
+}
+
+func copyFile(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,148 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"log"
+	"time"
+
+	"noticat/internal/bridge"
+	"noticat/internal/model"
+	"noticat/internal/notifier"
+	"noticat/internal/stream"
+	"noticat/pkg/common"
+	"noticat/pkg/global"
+)
+
+// newNoticeMessage filterID is the filter which matched, 0: none or unknown
+func newNoticeMessage(sub *model.UserSubscription, filterID uint, client string, notice bridge.Notice) *notifier.Message {
+	msg := &notifier.Message{
+		Subject:        "[NotiCat]" + common.ShortenTitle(notice.Title),
+		Title:          notice.Title,
+		URL:            notice.URL,
+		Date:           notice.Date,
+		Client:         client,
+		SubscriptionID: sub.ID,
+	}
+	attachUnsubscribe(msg, sub.UserID, sub.ID, filterID)
+	return msg
+}
+
+// sharedTargets the NotifyTargets for every subscription of the user
+func sharedTargets(userID uint) []notifier.Target {
+	var extra []model.NotifyTarget
+	if err := global.DB.Where("user_id = ? AND subscription_id = 0", userID).Find(&extra).Error; err != nil {
+		log.Printf("查询用户 %d 的通知渠道失败: %v", userID, err)
+		return nil
+	}
+	return toTargets(extra)
+}
+
+// subscriptionTargets the email addresses and the NotifyTargets of this subscription
+func subscriptionTargets(sub *model.UserSubscription) []notifier.Target {
+	var targets []notifier.Target
+	for _, email := range subscriptionEmails(&sub.User, []uint{sub.ID})[sub.ID] {
+		targets = append(targets, notifier.Target{Channel: notifier.ChannelEmail, Address: email})
+	}
+
+	var extra []model.NotifyTarget
+	err := global.DB.
+		Where("user_id = ? AND subscription_id IN ?", sub.UserID, []uint{0, sub.ID}).
+		Find(&extra).Error
+	if err != nil {
+		log.Printf("查询订阅 %d 的通知渠道失败: %v", sub.ID, err)
+		return targets
+	}
+
+	return append(targets, toTargets(extra)...)
+}
+
+func toTargets(rows []model.NotifyTarget) []notifier.Target {
+	targets := make([]notifier.Target, 0, len(rows))
+	for _, t := range rows {
+		targets = append(targets, notifier.Target{
+			ID:      t.ID,
+			Channel: t.Channel,
+			Address: t.Address,
+			Secret:  t.Secret,
+		})
+	}
+	return targets
+}
+
+// holdNotice keep the notice until its digest is due or quiet hours end
+func holdNotice(sub *model.UserSubscription, userNoticeID uint, client string, notice bridge.Notice, reason string) {
+	holdPending(newPendingNotice(sub, userNoticeID, client, notice, reason))
+}
+
+// holdUpdate keep an edit of a delivered notice for the digest or the end of
+// quiet hours, with the diff it would have been sent with
+func holdUpdate(sub *model.UserSubscription, userNoticeID uint, client string, notice bridge.Notice, reason string, diff string) {
+	pending := newPendingNotice(sub, userNoticeID, client, notice, reason)
+	pending.Diff = diff
+	holdPending(pending)
+}
+
+func newPendingNotice(sub *model.UserSubscription, userNoticeID uint, client string, notice bridge.Notice, reason string) *model.PendingNotice {
+	return &model.PendingNotice{
+		UserID:         sub.UserID,
+		SubscriptionID: sub.ID,
+		Reason:         reason,
+		UserNoticeID:   userNoticeID,
+		Client:         client,
+		Title:          notice.Title,
+		URL:            notice.URL,
+		Date:           notice.Date,
+	}
+}
+
+func holdPending(pending *model.PendingNotice) {
+	if err := global.DB.Create(pending).Error; err != nil {
+		log.Printf("写入待发通知失败: %v", err)
+	}
+}
+
+// publishNotice push the notice to the live stream of the user
+func publishNotice(userID uint, msg *notifier.Message) {
+	stream.Publish(userID, stream.Event{
+		Type:           "notice",
+		Title:          msg.Title,
+		URL:            msg.URL,
+		Date:           msg.Date,
+		Client:         msg.Client,
+		SubscriptionID: msg.SubscriptionID,
+	})
+}
+
+// recordTargetStatus keep the result of the last delivery on NotifyTarget
+func recordTargetStatus(target *notifier.Target, sendErr error) {
+	if target.ID == 0 {
+		return
+	}
+
+	status, lastError := "ok", ""
+	if sendErr != nil {
+		status, lastError = "failed", sendErr.Error()
+	}
+
+	err := global.DB.Model(&model.NotifyTarget{}).Where("id = ?", target.ID).Updates(map[string]any{
+		"last_status":  status,
+		"last_error":   lastError,
+		"last_sent_at": time.Now(),
+	}).Error
+	if err != nil {
+		log.Printf("更新通知渠道 %d 状态失败: %v", target.ID, err)
+	}
+}
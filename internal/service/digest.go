@@ -0,0 +1,235 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"noticat/internal/model"
+	"noticat/internal/notifier"
+	"noticat/pkg/global"
+)
+
+const defaultDigestTime = "08:00"
+
+// digestSlot the last time at or before now the digest was scheduled, now
+// for immediate (left over after the user switched back)
+func digestSlot(mode string, digestTime string, weekday int, now time.Time) time.Time {
+	at, err := time.Parse("15:04", digestTime)
+	if err != nil {
+		at, _ = time.Parse("15:04", defaultDigestTime)
+	}
+	y, m, d := now.Date()
+	today := time.Date(y, m, d, at.Hour(), at.Minute(), 0, 0, now.Location())
+
+	switch mode {
+	case model.DeliveryHourly:
+		return time.Date(y, m, d, now.Hour(), 0, 0, 0, now.Location())
+	case model.DeliveryDaily:
+		if today.After(now) {
+			return today.AddDate(0, 0, -1)
+		}
+		return today
+	case model.DeliveryWeekly:
+		slot := today.AddDate(0, 0, -((int(now.Weekday()) - weekday + 7) % 7))
+		if slot.After(now) {
+			return slot.AddDate(0, 0, -7)
+		}
+		return slot
+	}
+	return now
+}
+
+// digestDue a slot passed since the last digest of sub, or since its oldest
+// held notice when it never had one. a missed minute is caught up on the next
+func digestDue(sub *model.UserSubscription, oldest time.Time, now time.Time) bool {
+	mode, digestTime, weekday := sub.Delivery()
+	slot := digestSlot(mode, digestTime, weekday, now)
+	since := oldest
+	if sub.LastDigestAt != nil {
+		since = *sub.LastDigestAt
+	}
+	return slot.After(since)
+}
+
+// FlushDigests send the digests due by now, called by the scheduler every minute.
+// digests ignore quiet hours, the user picked the time
+func FlushDigests(now time.Time) {
+	var userIDs []uint
+	err := global.DB.Model(&model.PendingNotice{}).
+		Where("reason = ?", model.PendingDigest).
+		Distinct().
+		Pluck("user_id", &userIDs).Error
+	if err != nil {
+		log.Printf("[Digest] 查询待发摘要失败: %v", err)
+		return
+	}
+
+	for _, uid := range userIDs {
+		flushUserDigest(uid, now)
+	}
+}
+
+func flushUserDigest(userID uint, now time.Time) {
+	var pendings []model.PendingNotice
+	err := global.DB.Where("user_id = ? AND reason = ?", userID, model.PendingDigest).Order("id").Find(&pendings).Error
+	if err != nil {
+		log.Printf("[Digest] 查询用户 %d 的待发摘要失败: %v", userID, err)
+		return
+	}
+
+	var subs []model.UserSubscription
+	if err := global.DB.Preload("User").Where("user_id = ?", userID).Find(&subs).Error; err != nil {
+		log.Printf("[Digest] 查询用户 %d 的订阅失败: %v", userID, err)
+		return
+	}
+	if len(subs) == 0 {
+		// every subscription is gone
+		global.DB.Unscoped().Where("user_id = ?", userID).Delete(&model.PendingNotice{})
+		return
+	}
+
+	// digest time is in the timezone of the user
+	local := now.In(subs[0].User.Location(now.Location()))
+
+	oldest := make(map[uint]time.Time)
+	for _, p := range pendings {
+		if _, ok := oldest[p.SubscriptionID]; !ok {
+			oldest[p.SubscriptionID] = p.CreatedAt
+		}
+	}
+	dueSubs := make(map[uint]bool)
+	for i := range subs {
+		if first, ok := oldest[subs[i].ID]; ok && digestDue(&subs[i], first, local) {
+			dueSubs[subs[i].ID] = true
+		}
+	}
+
+	var due []model.PendingNotice
+	for _, p := range pendings {
+		if dueSubs[p.SubscriptionID] {
+			due = append(due, p)
+		}
+	}
+	// a run overlapping this one sends none of them twice
+	due = claimPending(due)
+	if len(due) == 0 {
+		return
+	}
+
+	user := subs[0].User
+
+	// every address gets the subscriptions routed to it
+	var dueSubIDs []uint
+	for id := range dueSubs {
+		dueSubIDs = append(dueSubIDs, id)
+	}
+	err = global.DB.Model(&model.UserSubscription{}).Where("id IN ?", dueSubIDs).Update("last_digest_at", now).Error
+	if err != nil {
+		log.Printf("[Digest] 记录用户 %d 的摘要时间失败: %v", userID, err)
+	}
+	emails := subscriptionEmails(&user, dueSubIDs)
+
+	byEmail := make(map[string][]model.PendingNotice)
+	var emailOrder []string
+	for _, p := range due {
+		for _, email := range emails[p.SubscriptionID] {
+			if _, ok := byEmail[email]; !ok {
+				emailOrder = append(emailOrder, email)
+			}
+			byEmail[email] = append(byEmail[email], p)
+		}
+	}
+	for _, email := range emailOrder {
+		deliverDigest(userID, byEmail[email], []notifier.Target{{Channel: notifier.ChannelEmail, Address: email}})
+	}
+
+	// the other channels get everything
+	if shared := sharedTargets(userID); len(shared) > 0 {
+		deliverDigest(userID, due, shared)
+	}
+
+	log.Printf("[Digest] 用户 %d 的摘要已发送，共 %d 条", userID, len(due))
+}
+
+func deliverDigest(userID uint, pendings []model.PendingNotice, targets []notifier.Target) {
+	noticeIDs := make([]uint, 0, len(pendings))
+	subIDs := make(map[uint]bool)
+	for _, p := range pendings {
+		noticeIDs = append(noticeIDs, p.UserNoticeID)
+		subIDs[p.SubscriptionID] = true
+	}
+
+	msg := &notifier.Message{
+		Subject: fmt.Sprintf("[NotiCat]摘要：%d 条新通知", len(pendings)),
+		Title:   fmt.Sprintf("NotiCat 摘要：%d 条新通知", len(pendings)),
+		Body:    renderDigest(pendings),
+	}
+	// one subscription: the link manages it, several: all of them
+	var subscriptionID uint
+	if len(subIDs) == 1 {
+		subscriptionID = pendings[0].SubscriptionID
+	}
+	attachUnsubscribe(msg, userID, subscriptionID, 0)
+	deliver(&recipient{
+		UserID:    userID,
+		NoticeIDs: noticeIDs,
+		Targets:   targets,
+	}, msg)
+}
+
+// renderDigest one html grouped by subscription
+func renderDigest(pendings []model.PendingNotice) string {
+	groups := make(map[uint][]model.PendingNotice)
+	var order []uint
+	for _, p := range pendings {
+		if _, ok := groups[p.SubscriptionID]; !ok {
+			order = append(order, p.SubscriptionID)
+		}
+		groups[p.SubscriptionID] = append(groups[p.SubscriptionID], p)
+	}
+	sort.Slice(order, func(i, j int) bool { return order[i] < order[j] })
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("<h2>NotiCat 摘要（%d 条新通知）</h2>", len(pendings)))
+	for _, subID := range order {
+		items := groups[subID]
+		sb.WriteString(fmt.Sprintf("<h3>%s · 订阅 #%d（%d 条）</h3><ul>", html.EscapeString(items[0].Client), subID, len(items)))
+		for _, p := range items {
+			sb.WriteString("<li>")
+			if p.Diff != "" {
+				sb.WriteString("<b>[更新]</b> ")
+			}
+			if p.URL != "" {
+				sb.WriteString(fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(p.URL), html.EscapeString(p.Title)))
+			} else {
+				sb.WriteString(html.EscapeString(p.Title))
+			}
+			if p.Date != "" {
+				sb.WriteString(" <small>" + html.EscapeString(p.Date) + "</small>")
+			}
+			// rendered by renderTextDiff, escaped already
+			sb.WriteString(p.Diff)
+			sb.WriteString("</li>")
+		}
+		sb.WriteString("</ul>")
+	}
+	return sb.String()
+}
@@ -0,0 +1,57 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"testing"
+	"time"
+
+	"noticat/internal/model"
+)
+
+func TestDigestDue(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	// Wednesday
+	at := func(day, hour, minute int) time.Time { return time.Date(2026, 3, day, hour, minute, 0, 0, loc) }
+	ptr := func(t time.Time) *time.Time { return &t }
+
+	cases := []struct {
+		name   string
+		sub    model.UserSubscription
+		oldest time.Time
+		now    time.Time
+		want   bool
+	}{
+		{"daily on time", model.UserSubscription{DeliveryMode: model.DeliveryDaily, DigestTime: "08:00"}, at(17, 22, 0), at(18, 8, 0), true},
+		{"daily before time", model.UserSubscription{DeliveryMode: model.DeliveryDaily, DigestTime: "08:00"}, at(17, 22, 0), at(18, 7, 59), false},
+		{"daily tick missed", model.UserSubscription{DeliveryMode: model.DeliveryDaily, DigestTime: "08:00"}, at(17, 22, 0), at(18, 8, 3), true},
+		{"daily held after the slot", model.UserSubscription{DeliveryMode: model.DeliveryDaily, DigestTime: "08:00"}, at(18, 9, 0), at(18, 10, 0), false},
+		{"daily sent already", model.UserSubscription{DeliveryMode: model.DeliveryDaily, DigestTime: "08:00", LastDigestAt: ptr(at(18, 8, 0))}, at(17, 22, 0), at(18, 8, 1), false},
+		{"daily sent yesterday", model.UserSubscription{DeliveryMode: model.DeliveryDaily, DigestTime: "08:00", LastDigestAt: ptr(at(17, 8, 0))}, at(18, 9, 0), at(19, 8, 0), true},
+		{"daily down for a day", model.UserSubscription{DeliveryMode: model.DeliveryDaily, DigestTime: "08:00", LastDigestAt: ptr(at(16, 8, 0))}, at(16, 9, 0), at(18, 6, 0), true},
+		{"hourly", model.UserSubscription{DeliveryMode: model.DeliveryHourly}, at(18, 9, 20), at(18, 10, 2), true},
+		{"hourly same hour", model.UserSubscription{DeliveryMode: model.DeliveryHourly}, at(18, 9, 20), at(18, 9, 59), false},
+		{"weekly on the day", model.UserSubscription{DeliveryMode: model.DeliveryWeekly, DigestTime: "09:30", DigestWeekday: 3}, at(16, 12, 0), at(18, 9, 31), true},
+		{"weekly other day", model.UserSubscription{DeliveryMode: model.DeliveryWeekly, DigestTime: "09:30", DigestWeekday: 5}, at(16, 12, 0), at(18, 9, 31), false},
+		{"weekly day missed", model.UserSubscription{DeliveryMode: model.DeliveryWeekly, DigestTime: "09:30", DigestWeekday: 1}, at(13, 12, 0), at(18, 9, 31), true},
+		{"follows the user", model.UserSubscription{User: model.User{DeliveryMode: model.DeliveryDaily, DigestTime: "21:00"}}, at(18, 9, 0), at(18, 21, 5), true},
+		{"switched back to immediate", model.UserSubscription{DeliveryMode: model.DeliveryImmediate}, at(18, 9, 0), at(18, 9, 1), true},
+	}
+	for _, tc := range cases {
+		if got := digestDue(&tc.sub, tc.oldest, tc.now); got != tc.want {
+			t.Errorf("%s: digestDue = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
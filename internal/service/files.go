@@ -0,0 +1,217 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"noticat/internal/filestore"
+	"noticat/internal/model"
+	"noticat/internal/notifier"
+	"noticat/pkg/global"
+)
+
+var ErrInvalidFileLink = errors.New("下载链接无效或已过期")
+
+// storeAttachment put a downloaded attachment of a notice into the file store
+func storeAttachment(client, noticeHash, sourceURL, path string) (string, int64, error) {
+	hash, size, err := filestore.Put(path)
+	if err != nil {
+		return "", 0, err
+	}
+
+	now := time.Now()
+	err = global.DB.Transaction(func(tx *gorm.DB) error {
+		file := model.StoredFile{Hash: hash, Size: size, LastUsedAt: now}
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "hash"}},
+			DoUpdates: clause.Assignments(map[string]any{"last_used_at": now, "deleted_at": nil}),
+		}).Create(&file).Error; err != nil {
+			return err
+		}
+
+		link := model.NoticeFile{
+			Client:     client,
+			NoticeHash: noticeHash,
+			SourceURL:  sourceURL,
+			Name:       filepath.Base(path),
+			FileHash:   hash,
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "client"}, {Name: "notice_hash"}, {Name: "source_url"}},
+			DoUpdates: clause.AssignmentColumns([]string{"name", "file_hash", "updated_at"}),
+		}).Create(&link).Error
+	})
+	if err != nil {
+		return "", 0, fmt.Errorf("记录附件失败: %w", err)
+	}
+	return hash, size, nil
+}
+
+// storedAttachment the stored blob of an attachment downloaded before, if still there
+func storedAttachment(client, noticeHash, sourceURL string) (*model.NoticeFile, int64, bool) {
+	var link model.NoticeFile
+	err := global.DB.Where("client = ? AND notice_hash = ? AND source_url = ?", client, noticeHash, sourceURL).First(&link).Error
+	if err != nil {
+		return nil, 0, false
+	}
+
+	var file model.StoredFile
+	if err := global.DB.Where("hash = ?", link.FileHash).First(&file).Error; err != nil {
+		return nil, 0, false
+	}
+	if !filestore.Exists(file.Hash) {
+		return nil, 0, false
+	}
+
+	touchStoredFile(file.Hash)
+	return &link, file.Size, true
+}
+
+func touchStoredFile(hash string) {
+	global.DB.Model(&model.StoredFile{}).Where("hash = ?", hash).Update("last_used_at", time.Now())
+}
+
+// linkStoredFile point the i-th attachment link of msg at the file store,
+// the source url often needs a login
+func linkStoredFile(msg *notifier.Message, i int, hash, name string) {
+	if i >= len(msg.Links) {
+		return
+	}
+	url, err := FileURL(hash, name, attachLinkTTL())
+	if err != nil {
+		return
+	}
+	msg.Links[i].URL = url
+}
+
+type fileClaims struct {
+	Hash string `json:"hash"`
+	Name string `json:"name"`
+	jwt.RegisteredClaims
+}
+
+// fileKey not the login key, so a link never works as a login token
+func fileKey() []byte {
+	return append([]byte("files:"), global.JwtSecret...)
+}
+
+// FileURL a signed download link of a stored file, the file is not
+// collected before the link expires
+func FileURL(hash, name string, ttl time.Duration) (string, error) {
+	expires := time.Now().Add(ttl)
+	claims := fileClaims{
+		Hash: hash,
+		Name: name,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "file",
+			ExpiresAt: jwt.NewNumericDate(expires),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(fileKey())
+	if err != nil {
+		return "", err
+	}
+	global.DB.Model(&model.StoredFile{}).
+		Where("hash = ? AND (linked_until IS NULL OR linked_until < ?)", hash, expires).
+		Update("linked_until", expires)
+	return fmt.Sprintf("%s/files/%s", global.PublicURL, token), nil
+}
+
+// ResolveFileToken the blob path and the file name behind a download link,
+// a download is not a use: the link already keeps the file until it expires
+func ResolveFileToken(tokenString string) (string, string, error) {
+	var claims fileClaims
+	token, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (any, error) {
+		return fileKey(), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithSubject("file"))
+	if err != nil || !token.Valid {
+		return "", "", ErrInvalidFileLink
+	}
+
+	path, err := filestore.Path(claims.Hash)
+	if err != nil {
+		return "", "", ErrInvalidFileLink
+	}
+	return path, filepath.Base(claims.Name), nil
+}
+
+// CollectStoredFiles remove the files unused for NOTICAT_FILE_RETENTION_DAYS,
+// then the least recently used ones until the store fits NOTICAT_FILE_QUOTA_MB.
+// Files behind an unexpired download link are kept, the store may stay over
+// the quota until the links expire
+func CollectStoredFiles(now time.Time) {
+	retention := time.Duration(atoiOr(global.FileRetentionDays, 30)) * 24 * time.Hour
+	quota := int64(atoiOr(global.FileStoreQuotaMB, 2048)) << 20
+	const unlinked = "(linked_until IS NULL OR linked_until <= ?)"
+
+	var expired []model.StoredFile
+	if err := global.DB.Where(unlinked, now).Where("last_used_at < ?", now.Add(-retention)).Find(&expired).Error; err != nil {
+		log.Printf("[Files] 查询过期文件失败: %v", err)
+		return
+	}
+	removed := removeStoredFiles(expired)
+
+	var total int64
+	if err := global.DB.Model(&model.StoredFile{}).Select("COALESCE(SUM(size), 0)").Scan(&total).Error; err != nil {
+		log.Printf("[Files] 统计文件存储失败: %v", err)
+		return
+	}
+	for total > quota {
+		var oldest []model.StoredFile
+		if err := global.DB.Where(unlinked, now).Order("last_used_at").Limit(50).Find(&oldest).Error; err != nil || len(oldest) == 0 {
+			break
+		}
+		var batch []model.StoredFile
+		for _, f := range oldest {
+			if total <= quota {
+				break
+			}
+			batch = append(batch, f)
+			total -= f.Size
+		}
+		n := removeStoredFiles(batch)
+		if n == 0 {
+			break
+		}
+		removed += n
+	}
+
+	if removed > 0 {
+		log.Printf("[Files] 已清理 %d 个文件", removed)
+	}
+}
+
+func removeStoredFiles(files []model.StoredFile) int {
+	removed := 0
+	for _, f := range files {
+		if err := filestore.Remove(f.Hash); err != nil {
+			log.Printf("[Files] 删除文件 %s 失败: %v", f.Hash, err)
+			continue
+		}
+		global.DB.Unscoped().Where("file_hash = ?", f.Hash).Delete(&model.NoticeFile{})
+		global.DB.Unscoped().Delete(&model.StoredFile{}, f.ID)
+		removed++
+	}
+	return removed
+}
@@ -0,0 +1,88 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"noticat/internal/filestore"
+	"noticat/internal/model"
+	"noticat/pkg/global"
+)
+
+func TestCollectStoredFiles(t *testing.T) {
+	useTestDB(t)
+	savedDir, savedDays := global.FileStoreDir, global.FileRetentionDays
+	global.FileStoreDir, global.FileRetentionDays = t.TempDir(), "30"
+	t.Cleanup(func() { global.FileStoreDir, global.FileRetentionDays = savedDir, savedDays })
+
+	put := func(name string) string {
+		path := filepath.Join(t.TempDir(), name)
+		if err := os.WriteFile(path, []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		hash, _, err := storeAttachment("test", "notice", "https://example.com/"+name, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hash
+	}
+	unused, linked := put("unused.pdf"), put("linked.pdf")
+
+	now := time.Now()
+	global.DB.Model(&model.StoredFile{}).Where("1 = 1").Update("last_used_at", now.AddDate(0, 0, -60))
+
+	url, err := FileURL(linked, "linked.pdf", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// downloading is not a use
+	if _, _, err := ResolveFileToken(url[strings.LastIndex(url, "/")+1:]); err != nil {
+		t.Fatal(err)
+	}
+	var file model.StoredFile
+	global.DB.Where("hash = ?", linked).First(&file)
+	if file.LastUsedAt.After(now) {
+		t.Errorf("download touched the file: %v", file.LastUsedAt)
+	}
+
+	CollectStoredFiles(now)
+	if filestore.Exists(unused) {
+		t.Error("unused file was kept")
+	}
+	if !filestore.Exists(linked) {
+		t.Fatal("file behind a live link was collected")
+	}
+
+	// over the quota, but the link still works
+	savedQuota := global.FileStoreQuotaMB
+	global.FileStoreQuotaMB = "1"
+	t.Cleanup(func() { global.FileStoreQuotaMB = savedQuota })
+	global.DB.Model(&model.StoredFile{}).Where("hash = ?", linked).
+		Updates(map[string]any{"last_used_at": now, "size": 2 << 20})
+	CollectStoredFiles(now)
+	if !filestore.Exists(linked) {
+		t.Fatal("quota evicted a file behind a live link")
+	}
+
+	CollectStoredFiles(now.Add(2 * time.Hour))
+	if filestore.Exists(linked) {
+		t.Error("file kept after its link expired")
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"errors"
+	"time"
+
+	"noticat/internal/model"
+	"noticat/pkg/global"
+)
+
+var ErrNoticeNotFound = errors.New("通知不存在")
+
+// archived filter of the inbox
+const (
+	ArchivedExclude = "false"
+	ArchivedOnly    = "true"
+	ArchivedAll     = "all"
+)
+
+// InboxSortPublished order the inbox by the publish date of the notices
+const InboxSortPublished = "published"
+
+type InboxQuery struct {
+	SubscriptionID uint
+	Client         string
+	Unread         bool
+	Starred        bool
+	Archived       string
+	// "published": newest publish date first, otherwise newest received first
+	Sort     string
+	Page     int
+	PageSize int
+}
+
+// ListInbox a page of the notices of the user, newest first, without the body
+func ListInbox(userID uint, q *InboxQuery) ([]NoticeItem, int64, error) {
+	query := userNoticeItems(userID, false)
+	if q.SubscriptionID != 0 {
+		query = query.Where("un.subscription_id = ?", q.SubscriptionID)
+	}
+	if q.Client != "" {
+		query = query.Where("n.client = ?", q.Client)
+	}
+	if q.Unread {
+		query = query.Where("un.read_at IS NULL")
+	}
+	if q.Starred {
+		query = query.Where("un.starred = ?", true)
+	}
+	switch q.Archived {
+	case ArchivedOnly:
+		query = query.Where("un.archived_at IS NOT NULL")
+	case ArchivedAll:
+	default:
+		query = query.Where("un.archived_at IS NULL")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if q.Sort == InboxSortPublished {
+		query = query.Order("n.published_at IS NULL, n.published_at DESC")
+	}
+
+	var items []NoticeItem
+	err := query.Order("un.id DESC").
+		Offset((q.Page - 1) * q.PageSize).
+		Limit(q.PageSize).
+		Scan(&items).Error
+	return items, total, err
+}
+
+// GetInboxNotice one notice of the user with its body
+func GetInboxNotice(userID uint, userNoticeID uint) (*NoticeItem, error) {
+	var item NoticeItem
+	result := userNoticeItems(userID, true).Where("un.id = ?", userNoticeID).Limit(1).Scan(&item)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrNoticeNotFound
+	}
+	return &item, nil
+}
+
+// NoticeState changes of the inbox state, nil: unchanged
+type NoticeState struct {
+	Read     *bool
+	Starred  *bool
+	Archived *bool
+}
+
+func (s *NoticeState) updates(now time.Time) map[string]any {
+	updates := make(map[string]any)
+	if s.Read != nil {
+		updates["read_at"] = timeOrNil(*s.Read, now)
+	}
+	if s.Starred != nil {
+		updates["starred"] = *s.Starred
+	}
+	if s.Archived != nil {
+		updates["archived_at"] = timeOrNil(*s.Archived, now)
+	}
+	return updates
+}
+
+func timeOrNil(set bool, now time.Time) any {
+	if set {
+		return now
+	}
+	return nil
+}
+
+// UpdateNoticeState apply state to the notices of the user, returns how many changed
+func UpdateNoticeState(userID uint, ids []uint, state *NoticeState) (int64, error) {
+	updates := state.updates(time.Now())
+	if len(updates) == 0 || len(ids) == 0 {
+		return 0, nil
+	}
+
+	result := global.DB.Model(&model.UserNotice{}).
+		Where("user_id = ? AND subscription_id <> 0 AND id IN ?", userID, ids).
+		Updates(updates)
+	return result.RowsAffected, result.Error
+}
+
+// MarkAllRead every unread notice of the user, of one subscription if given
+func MarkAllRead(userID uint, subscriptionID uint) (int64, error) {
+	query := global.DB.Model(&model.UserNotice{}).
+		Where("user_id = ? AND subscription_id <> 0 AND read_at IS NULL", userID)
+	if subscriptionID != 0 {
+		query = query.Where("subscription_id = ?", subscriptionID)
+	}
+	result := query.Update("read_at", time.Now())
+	return result.RowsAffected, result.Error
+}
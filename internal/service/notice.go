@@ -0,0 +1,168 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+
+	"noticat/internal/bridge"
+	"noticat/internal/model"
+	"noticat/internal/search"
+	"noticat/pkg/global"
+)
+
+// NoticeItem a notice as the user received it
+type NoticeItem struct {
+	UserNoticeID   uint
+	SubscriptionID uint
+	Client         string
+	ContentHash    string
+	Title          string
+	URL            string
+	Date           string
+	Deadline       string
+	PublishedAt    *time.Time
+	Body           string
+	ReceivedAt     time.Time
+	UpdatedAt      time.Time
+	DeliveredAt    *time.Time
+	ReadAt         *time.Time
+	Starred        bool
+	ArchivedAt     *time.Time
+}
+
+// recordMatch keep the content of a matched notice and tie it to the UserNotice
+func recordMatch(un *model.UserNotice, subscriptionID uint, client string, notice bridge.Notice) {
+	n := model.Notice{Client: client, ContentHash: notice.ContentHash()}
+	err := global.DB.
+		Where(&n).
+		Assign(model.Notice{Title: notice.Title, URL: notice.URL, Date: notice.Date, Deadline: notice.Deadline, PublishedAt: notice.PublishedAt}).
+		FirstOrCreate(&n).Error
+	if err != nil {
+		log.Printf("保存通知内容失败: %v", err)
+		return
+	}
+	indexNotice(n.ID)
+
+	un.SubscriptionID, un.NoticeID = subscriptionID, n.ID
+	err = global.DB.Model(&model.UserNotice{}).Where("id = ?", un.ID).Updates(map[string]any{
+		"subscription_id": subscriptionID,
+		"notice_id":       n.ID,
+	}).Error
+	if err != nil {
+		log.Printf("更新 UserNotice 失败: %v", err)
+	}
+}
+
+// indexNotice keep the search index in sync with the row, a failed index never
+// fails the write
+func indexNotice(id uint) {
+	if err := search.Index(global.DB, id); err != nil {
+		log.Printf("更新搜索索引失败: %v", err)
+	}
+}
+
+// saveNoticeDetail keep the fetched detail of a notice. edited: it had a detail
+// before and the text changed, previous is the old text then
+func saveNoticeDetail(client string, notice bridge.Notice, detail *bridge.Detail) (n *model.Notice, previous string, edited bool) {
+	// enrich runs before the match is recorded, the row may not be there yet
+	n = &model.Notice{}
+	err := global.DB.
+		Where(model.Notice{Client: client, ContentHash: notice.ContentHash()}).
+		Attrs(model.Notice{Title: notice.Title, URL: notice.URL, Date: notice.Date, Deadline: notice.Deadline, PublishedAt: notice.PublishedAt}).
+		FirstOrCreate(n).Error
+
+	text := detailText(detail.Body, detail.Attachments)
+	fingerprint := textFingerprint(text)
+	// an empty page is more likely a failed login than an edit
+	edited = n.Fingerprint != "" && n.Fingerprint != fingerprint && text != ""
+	previous = n.Text
+
+	now := time.Now()
+	if err == nil {
+		updates := map[string]any{"body": detail.Body, "detail_at": now, "checked_at": now}
+		if text != "" {
+			updates["text"] = text
+			updates["fingerprint"] = fingerprint
+		}
+		err = global.DB.Model(n).Updates(updates).Error
+	}
+	if err != nil {
+		log.Printf("保存通知详情失败: %v", err)
+		return nil, "", false
+	}
+	indexNotice(n.ID)
+	return n, previous, edited
+}
+
+// userNoticeItems the matched notices of the user, scan into NoticeItem. the
+// body is only read when asked for, lists go without it
+func userNoticeItems(userID uint, withBody bool) *gorm.DB {
+	columns := `un.id AS user_notice_id, un.subscription_id, n.client, n.content_hash, n.title, n.url, n.date, n.deadline, n.published_at,
+			un.created_at AS received_at, n.updated_at, un.delivered_at, un.read_at, un.starred, un.archived_at`
+	if withBody {
+		columns += ", n.body"
+	}
+	return global.DB.Table("user_notices AS un").
+		Select(columns).
+		Joins("JOIN notices AS n ON n.id = un.notice_id AND n.deleted_at IS NULL").
+		Where("un.user_id = ? AND un.subscription_id <> 0 AND un.deleted_at IS NULL", userID)
+}
+
+// RecentNotices the latest matched notices of the user, newest first
+func RecentNotices(userID uint, limit int) ([]NoticeItem, error) {
+	var items []NoticeItem
+	err := userNoticeItems(userID, true).Order("un.id DESC").Limit(limit).Scan(&items).Error
+	return items, err
+}
+
+func newFeedToken() string {
+	b := make([]byte, 20)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// FeedToken the feed token of the user, created on first use
+func FeedToken(userID uint) (string, error) {
+	var user model.User
+	if err := global.DB.Select("id", "feed_token").First(&user, userID).Error; err != nil {
+		return "", err
+	}
+	if user.FeedToken != "" {
+		return user.FeedToken, nil
+	}
+	return RotateFeedToken(userID)
+}
+
+// RotateFeedToken a new feed token, the old urls stop working
+func RotateFeedToken(userID uint) (string, error) {
+	token := newFeedToken()
+	err := global.DB.Model(&model.User{}).Where("id = ?", userID).Update("feed_token", token).Error
+	return token, err
+}
+
+// UserByFeedToken the owner of a feed token
+func UserByFeedToken(token string) (*model.User, error) {
+	var user model.User
+	if err := global.DB.Where("feed_token = ? AND feed_token <> ''", token).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
@@ -0,0 +1,554 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"noticat/internal/bridge"
+	"noticat/internal/model"
+	"noticat/internal/notifier"
+	"noticat/pkg/common"
+	"noticat/pkg/global"
+)
+
+// built-in stages, registered stages go after one of them
+const (
+	StageFilter = "filter"
+	StageEnrich = "enrich"
+)
+
+// Batch the output of fetch: what a task brought and who subscribes to it
+type Batch struct {
+	TaskID      uint
+	Ctx         *FetchContext
+	Notices     []bridge.Notice
+	Subscribers []*Subscriber
+}
+
+// Subscriber a subscription of the task with its filters compiled
+type Subscriber struct {
+	Sub       *model.UserSubscription
+	filters   []*common.Expr
+	filterIDs []uint
+	// has filters, even if all of them are muted
+	filtered bool
+}
+
+// Item one new notice for one subscriber, filled in as it goes down the pipeline
+type Item struct {
+	Notice       bridge.Notice
+	Subscriber   *Subscriber
+	UserNoticeID uint
+
+	// set by filter
+	FilterID uint
+	// model.PendingDigest or model.PendingQuiet, "": deliver now
+	Hold string
+	Msg  *notifier.Message
+
+	// set by enrich for the items delivered now. Body is the detail, the title
+	// when there is none. Content is shared by every item of the notice, read only
+	Body    string
+	Content *noticeContent
+
+	// released from quiet hours: recorded and published when it was held
+	Released bool
+}
+
+// Stage a step after diff, it may change or drop items. a stage that fails is
+// skipped, the items go on as they were
+type Stage struct {
+	Name string
+	Run  func(ctx *FetchContext, items []*Item) ([]*Item, error)
+}
+
+// fetchContent the detail of a notice for enrichStage, tests swap it
+var fetchContent = fetchNoticeContent
+
+var (
+	stagesMu sync.RWMutex
+	stages   = []Stage{
+		{Name: StageFilter, Run: filterStage},
+		{Name: StageEnrich, Run: enrichStage},
+	}
+)
+
+// RegisterStage insert s right after the stage named after, e.g. a translation
+// or sanitizing stage after StageEnrich
+func RegisterStage(after string, s Stage) error {
+	stagesMu.Lock()
+	defer stagesMu.Unlock()
+
+	idx := -1
+	for i, st := range stages {
+		if st.Name == s.Name {
+			return fmt.Errorf("阶段 %s 已存在", s.Name)
+		}
+		if st.Name == after {
+			idx = i
+		}
+	}
+	if idx < 0 {
+		return fmt.Errorf("阶段 %s 不存在", after)
+	}
+	stages = append(stages[:idx+1], append([]Stage{s}, stages[idx+1:]...)...)
+	return nil
+}
+
+// DispatchMail send the new notices of a task to its subscribers, through
+//
+//	fetch -> diff -> filter -> enrich -> [registered stages] -> deliver -> recheck
+//
+// fetch, diff and recheck work on the whole task, the rest on the items of one
+// notice at a time
+func DispatchMail(taskID uint) {
+	rep := newPipelineReport()
+	defer rep.log(taskID)
+
+	start := time.Now()
+	batch, err := fetchStage(taskID)
+	if err != nil {
+		rep.observe("fetch", 0, 0, time.Since(start), err)
+		log.Printf("任务 %d 失败: %v", taskID, err)
+		return
+	}
+	rep.observe("fetch", 1, len(batch.Notices), time.Since(start), nil)
+
+	if err := dispatchBatch(batch, rep); err != nil {
+		log.Printf("任务 %d 失败: %v", taskID, err)
+		return
+	}
+
+	// known notices may have been edited since
+	start = time.Now()
+	checked := recheckStage(batch)
+	rep.observe("recheck", len(batch.Notices), checked, time.Since(start), nil)
+}
+
+// dispatchBatch diff the batch and run the new items through the stages, one
+// notice at a time
+func dispatchBatch(batch *Batch, rep *pipelineReport) error {
+	start := time.Now()
+	fresh, err := diffStage(batch)
+	if err != nil {
+		rep.observe("diff", len(batch.Notices), 0, time.Since(start), err)
+		return err
+	}
+	count := 0
+	for _, items := range fresh {
+		count += len(items)
+	}
+	rep.observe("diff", len(batch.Notices)*len(batch.Subscribers), count, time.Since(start), nil)
+
+	for i := range batch.Notices {
+		items := fresh[i]
+		if len(items) == 0 {
+			continue
+		}
+
+		items = runStages(batch.Ctx, items, StageFilter, rep)
+
+		start = time.Now()
+		deliverStage(batch.Ctx, items)
+		rep.observe("deliver", len(items), len(items), time.Since(start), nil)
+	}
+	return nil
+}
+
+// runStages run the items through the stages from the one named from
+func runStages(ctx *FetchContext, items []*Item, from string, rep *pipelineReport) []*Item {
+	stagesMu.RLock()
+	pipeline := append([]Stage(nil), stages...)
+	stagesMu.RUnlock()
+
+	started := false
+	for _, st := range pipeline {
+		if st.Name == from {
+			started = true
+		}
+		if !started || len(items) == 0 {
+			continue
+		}
+
+		start := time.Now()
+		out, err := st.Run(ctx, items)
+		rep.observe(st.Name, len(items), len(out), time.Since(start), err)
+		if err != nil {
+			log.Printf("[Pipeline] 阶段 %s 失败，已跳过: %v", st.Name, err)
+			continue
+		}
+		items = out
+	}
+	return items
+}
+
+// fetchStage run the task and load its subscribers
+func fetchStage(taskID uint) (*Batch, error) {
+	fetchCtx, notices, err := FetchByTaskID(taskID)
+	if err != nil {
+		return nil, err
+	}
+	if len(notices) == 0 {
+		return nil, fmt.Errorf("取到了空的Notices")
+	}
+
+	// client check (just a check)
+	if clientCheck := bridge.Client(strings.ToLower(fetchCtx.Client)); !clientCheck.IsValid() {
+		return nil, fmt.Errorf("错误的client: %s", fetchCtx.Client)
+	}
+
+	sortByPublished(notices, ServerLocation())
+
+	// find user who need this task
+	var subscriptions []model.UserSubscription
+	err = global.DB.
+		Preload("Filters").
+		Preload("User").
+		Where("task_id = ? AND paused = ?", taskID, false).
+		Find(&subscriptions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// compile the filters once for every notice
+	subscribers := make([]*Subscriber, 0, len(subscriptions))
+	for i := range subscriptions {
+		subscribers = append(subscribers, newSubscriber(&subscriptions[i]))
+	}
+
+	return &Batch{TaskID: taskID, Ctx: fetchCtx, Notices: notices, Subscribers: subscribers}, nil
+}
+
+// sortByPublished parse the dates and put the notices oldest first, so they go
+// out in the order they were published. the ones without a date go last, in
+// the order of the list
+func sortByPublished(notices []bridge.Notice, loc *time.Location) {
+	for i := range notices {
+		if t, _, ok := common.ParseDate(notices[i].Date, loc); ok {
+			notices[i].PublishedAt = &t
+		}
+	}
+	slices.SortStableFunc(notices, func(a, b bridge.Notice) int {
+		switch {
+		case a.PublishedAt == nil && b.PublishedAt == nil:
+			return 0
+		case a.PublishedAt == nil:
+			return 1
+		case b.PublishedAt == nil:
+			return -1
+		}
+		return a.PublishedAt.Compare(*b.PublishedAt)
+	})
+}
+
+// tooOld published before the day MaxAgeDays ago in the timezone of the
+// subscriber, a notice without a date never is
+func tooOld(sub *model.UserSubscription, notice bridge.Notice, now time.Time) bool {
+	if sub.MaxAgeDays <= 0 || notice.PublishedAt == nil {
+		return false
+	}
+	loc := sub.User.Location(ServerLocation())
+	y, m, d := now.In(loc).AddDate(0, 0, -sub.MaxAgeDays).Date()
+	return notice.PublishedAt.Before(time.Date(y, m, d, 0, 0, 0, 0, loc))
+}
+
+func newSubscriber(sub *model.UserSubscription) *Subscriber {
+	s := &Subscriber{
+		Sub:       sub,
+		filters:   make([]*common.Expr, 0, len(sub.Filters)),
+		filterIDs: make([]uint, 0, len(sub.Filters)),
+	}
+	muted := false
+	for _, f := range sub.Filters {
+		if f.Muted {
+			muted = true
+			continue
+		}
+		source := f.Expression
+		if source == "" {
+			source = common.LegacyFilterExpr(f.Pattern, f.Type == "regex", f.IgnoreCase)
+		}
+		expr, err := common.CompileExpr(source, ServerLocation())
+		if err != nil {
+			log.Printf("过滤规则 %d 无效，已忽略: %v", f.ID, err)
+			continue
+		}
+		s.filters = append(s.filters, expr)
+		s.filterIDs = append(s.filterIDs, f.ID)
+	}
+	s.filtered = len(s.filters) > 0 || muted
+	return s
+}
+
+// match the id of the first matching filter, 0 when the subscription has none
+func (s *Subscriber) match(notice bridge.Notice) (uint, bool) {
+	if !s.filtered {
+		return 0, true
+	}
+	doc := noticeDoc{notice: &notice}
+	now := time.Now()
+	for i, expr := range s.filters {
+		if expr.Match(doc, now) {
+			return s.filterIDs[i], true
+		}
+	}
+	return 0, false
+}
+
+// noticeDoc the fields of a notice a filter expression sees
+type noticeDoc struct {
+	notice *bridge.Notice
+}
+
+func (d noticeDoc) Text(field string) string {
+	if field == "url" {
+		return d.notice.URL
+	}
+	return d.notice.Title
+}
+
+func (d noticeDoc) Time(field string) (time.Time, bool) {
+	switch field {
+	case "date":
+		if d.notice.PublishedAt != nil {
+			return *d.notice.PublishedAt, true
+		}
+		t, _, ok := common.ParseDate(d.notice.Date, ServerLocation())
+		return t, ok
+	case "deadline":
+		t, _, ok := common.ParseDate(d.notice.Deadline, ServerLocation())
+		return t, ok
+	}
+	return time.Time{}, false
+}
+
+// diffStage the new items of every notice of the batch, same order as
+// batch.Notices. the seen set is loaded once and the new rows are inserted in bulk
+func diffStage(batch *Batch) ([][]*Item, error) {
+	items := make([][]*Item, len(batch.Notices))
+	if len(batch.Subscribers) == 0 {
+		return items, nil
+	}
+
+	hashes := make([]string, len(batch.Notices))
+	for i, notice := range batch.Notices {
+		hashes[i] = notice.ContentHash()
+	}
+	userIDs := make([]uint, 0, len(batch.Subscribers))
+	for _, s := range batch.Subscribers {
+		userIDs = append(userIDs, s.Sub.UserID)
+	}
+
+	known, err := knownNotices(batch.Ctx.Client, userIDs, hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	// a user subscribing twice, or a notice listed twice, is new only once
+	type pending struct {
+		notice int
+		sub    *Subscriber
+	}
+	var rows []seenRow
+	owners := make(map[string]pending)
+	for i, hash := range hashes {
+		for _, s := range batch.Subscribers {
+			key := seenKey(s.Sub.UserID, hash)
+			if known[key] {
+				continue
+			}
+			known[key] = true
+			rows = append(rows, seenRow{UserID: s.Sub.UserID, ContentHash: hash})
+			owners[key] = pending{notice: i, sub: s}
+		}
+	}
+	if len(rows) == 0 {
+		return items, nil
+	}
+
+	inserted, err := insertSeen(batch.Ctx.Client, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	// keep the order of the subscribers within a notice
+	ids := make(map[string]uint, len(inserted))
+	for _, r := range inserted {
+		ids[seenKey(r.UserID, r.ContentHash)] = r.ID
+	}
+	for _, r := range rows {
+		key := seenKey(r.UserID, r.ContentHash)
+		id, ok := ids[key]
+		if !ok {
+			continue
+		}
+		p := owners[key]
+		items[p.notice] = append(items[p.notice], &Item{Notice: batch.Notices[p.notice], Subscriber: p.sub, UserNoticeID: id})
+	}
+	return items, nil
+}
+
+// filterStage keep the recent enough items matching the filters of their
+// subscription and decide when they go out. it only looks, the inbox and the
+// live stream are written by deliverStage
+func filterStage(ctx *FetchContext, items []*Item) ([]*Item, error) {
+	now := time.Now()
+	matched := items[:0]
+	for _, it := range items {
+		sub := it.Subscriber.Sub
+		// a site reshuffling its list brings back stale notices
+		if tooOld(sub, it.Notice, now) {
+			continue
+		}
+		filterID, ok := it.Subscriber.match(it.Notice)
+		if !ok {
+			continue
+		}
+
+		it.FilterID = filterID
+		it.Msg = newNoticeMessage(sub, filterID, ctx.Client, it.Notice)
+
+		if mode, _, _ := sub.Delivery(); mode != model.DeliveryImmediate {
+			// digest: keep it for the next one
+			it.Hold = model.PendingDigest
+		} else if !sub.Urgent && inQuietHours(&sub.User, now) {
+			// quiet hours: release it when the window ends
+			it.Hold = model.PendingQuiet
+		}
+		matched = append(matched, it)
+	}
+	return matched, nil
+}
+
+// enrichStage fetch the detail and attachments once for the items delivered
+// now or in a digest. a digest only lists the titles, but nothing else would
+// ever fetch the detail of its notices for the inbox. quiet hours are enriched
+// by the release
+func enrichStage(ctx *FetchContext, items []*Item) ([]*Item, error) {
+	// all items of a run share the notice, but the quiet release mixes them
+	contents := make(map[string]*noticeContent)
+	for _, it := range items {
+		if it.Hold == model.PendingQuiet {
+			continue
+		}
+
+		key := it.Notice.ContentHash()
+		content, ok := contents[key]
+		if !ok {
+			var err error
+			content, err = fetchContent(ctx, it.Notice)
+			if err != nil {
+				// if non detail: just send title
+				log.Printf("non detail: %v", err)
+			}
+			contents[key] = content
+		}
+
+		it.Content = content
+		it.Body = it.Notice.Title
+		if content != nil {
+			it.Body = content.Body
+		}
+	}
+	return items, nil
+}
+
+// deliverStage record the matches, then hold the items for later or queue them
+// for delivery
+func deliverStage(ctx *FetchContext, items []*Item) {
+	for _, it := range items {
+		sub := it.Subscriber.Sub
+		if !it.Released {
+			un := model.UserNotice{}
+			un.ID = it.UserNoticeID
+			recordMatch(&un, sub.ID, ctx.Client, it.Notice)
+			publishNotice(sub.UserID, it.Msg)
+		}
+
+		if it.Hold != "" {
+			holdNotice(sub, it.UserNoticeID, ctx.Client, it.Notice, it.Hold)
+			continue
+		}
+
+		rcpt := &recipient{
+			UserID:    sub.UserID,
+			NoticeIDs: []uint{it.UserNoticeID},
+			Targets:   subscriptionTargets(sub),
+		}
+		if it.Content == nil {
+			it.Msg.Body = it.Body
+			deliver(rcpt, it.Msg)
+			continue
+		}
+		deliverContent(rcpt, it.Msg, it.Body, it.Content)
+	}
+}
+
+type stageMetric struct {
+	runs   int
+	in     int
+	out    int
+	took   time.Duration
+	errors int
+}
+
+// pipelineReport what every stage did in one run of a task
+type pipelineReport struct {
+	order   []string
+	metrics map[string]*stageMetric
+}
+
+func newPipelineReport() *pipelineReport {
+	return &pipelineReport{metrics: make(map[string]*stageMetric)}
+}
+
+func (r *pipelineReport) observe(stage string, in, out int, took time.Duration, err error) {
+	if r == nil {
+		return
+	}
+	m, ok := r.metrics[stage]
+	if !ok {
+		m = &stageMetric{}
+		r.metrics[stage] = m
+		r.order = append(r.order, stage)
+	}
+	m.runs++
+	m.in += in
+	m.out += out
+	m.took += took
+	if err != nil {
+		m.errors++
+	}
+}
+
+// log one line per run: stage in->out time
+func (r *pipelineReport) log(taskID uint) {
+	parts := make([]string, 0, len(r.order))
+	for _, name := range r.order {
+		m := r.metrics[name]
+		part := fmt.Sprintf("%s %d→%d %s", name, m.in, m.out, m.took.Round(time.Millisecond))
+		if m.errors > 0 {
+			part += fmt.Sprintf(" (失败 %d)", m.errors)
+		}
+		parts = append(parts, part)
+	}
+	log.Printf("[Pipeline] 任务 %d: %s", taskID, strings.Join(parts, " | "))
+}
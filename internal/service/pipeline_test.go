@@ -0,0 +1,241 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"noticat/internal/bridge"
+	"noticat/internal/model"
+	"noticat/pkg/global"
+)
+
+func testItem(sub *model.UserSubscription, title string, date string) *Item {
+	notice := bridge.Notice{Title: title, URL: "https://example.com/" + title, Date: date}
+	if t, err := time.ParseInLocation("2006-01-02", date, ServerLocation()); err == nil {
+		notice.PublishedAt = &t
+	}
+	return &Item{Notice: notice, Subscriber: newSubscriber(sub)}
+}
+
+func TestFilterStage(t *testing.T) {
+	useTestDB(t)
+	now := time.Now().In(ServerLocation())
+	// a quiet window around now, whatever time the test runs
+	quiet := model.User{
+		Timezone:          ServerLocation().String(),
+		QuietStart:        now.Add(-time.Hour).Format("15:04"),
+		QuietEnd:          now.Add(time.Hour).Format("15:04"),
+		WeekendQuietStart: now.Add(-time.Hour).Format("15:04"),
+		WeekendQuietEnd:   now.Add(time.Hour).Format("15:04"),
+	}
+	filters := []model.SubscriptionFilter{{Expression: "奖学金 OR 保研"}}
+	filters[0].ID = 7
+
+	cases := []struct {
+		name     string
+		sub      model.UserSubscription
+		title    string
+		date     string
+		keep     bool
+		filterID uint
+		hold     string
+	}{
+		{name: "no filters", title: "任意通知", keep: true},
+		{name: "matching filter", sub: model.UserSubscription{Filters: filters}, title: "奖学金评定", keep: true, filterID: 7},
+		{name: "other filter", sub: model.UserSubscription{Filters: filters}, title: "考试安排"},
+		{name: "muted filters", sub: model.UserSubscription{Filters: []model.SubscriptionFilter{{Expression: "奖学金", Muted: true}}}, title: "奖学金评定"},
+		{name: "too old", sub: model.UserSubscription{MaxAgeDays: 7}, title: "旧通知", date: now.AddDate(0, 0, -30).Format("2006-01-02")},
+		{name: "recent enough", sub: model.UserSubscription{MaxAgeDays: 7}, title: "新通知", date: now.Format("2006-01-02"), keep: true},
+		{name: "no date is never too old", sub: model.UserSubscription{MaxAgeDays: 7}, title: "无日期", keep: true},
+		{name: "digest", sub: model.UserSubscription{DeliveryMode: model.DeliveryDaily, DigestTime: "08:00"}, title: "日报", keep: true, hold: model.PendingDigest},
+		{name: "quiet hours", sub: model.UserSubscription{User: quiet}, title: "深夜", keep: true, hold: model.PendingQuiet},
+		{name: "urgent in quiet hours", sub: model.UserSubscription{User: quiet, Urgent: true}, title: "紧急", keep: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.sub.ID, tc.sub.UserID = 1, 1
+			it := testItem(&tc.sub, tc.title, tc.date)
+			out, err := filterStage(&FetchContext{Client: "test"}, []*Item{it})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (len(out) == 1) != tc.keep {
+				t.Fatalf("kept %d items, want kept = %v", len(out), tc.keep)
+			}
+			if !tc.keep {
+				return
+			}
+			if it.FilterID != tc.filterID || it.Hold != tc.hold {
+				t.Errorf("filter %d hold %q, want %d %q", it.FilterID, it.Hold, tc.filterID, tc.hold)
+			}
+			if it.Msg == nil || it.Msg.Title != tc.title {
+				t.Errorf("msg = %+v", it.Msg)
+			}
+		})
+	}
+
+	// filtering only looks, the matches are written by deliverStage
+	var count int64
+	global.DB.Model(&model.Notice{}).Count(&count)
+	if count != 0 {
+		t.Errorf("filterStage wrote %d notices", count)
+	}
+}
+
+func TestEnrichStage(t *testing.T) {
+	calls := make(map[string]int)
+	fetchContent = func(ctx *FetchContext, notice bridge.Notice) (*noticeContent, error) {
+		calls[notice.Title]++
+		if notice.Title == "broken" {
+			return nil, errors.New("detail page is gone")
+		}
+		return &noticeContent{Body: "detail of " + notice.Title}, nil
+	}
+	t.Cleanup(func() { fetchContent = fetchNoticeContent })
+
+	sub := &model.UserSubscription{}
+	a1, a2 := testItem(sub, "a", ""), testItem(sub, "a", "")
+	held := testItem(sub, "held", "")
+	held.Hold = model.PendingQuiet
+	digest := testItem(sub, "digest", "")
+	digest.Hold = model.PendingDigest
+	broken := testItem(sub, "broken", "")
+
+	out, err := enrichStage(&FetchContext{Client: "test"}, []*Item{a1, a2, held, digest, broken})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 5 {
+		t.Fatalf("got %d items, want 5", len(out))
+	}
+
+	// the digest one too, for the body in the inbox
+	if want := map[string]int{"a": 1, "digest": 1, "broken": 1}; !maps.Equal(calls, want) {
+		t.Errorf("fetches = %v, want %v", calls, want)
+	}
+	if a1.Body != "detail of a" || a1.Content == nil || a1.Content != a2.Content {
+		t.Errorf("items of one notice should share the content: %+v %+v", a1, a2)
+	}
+	if held.Body != "" || held.Content != nil {
+		t.Errorf("item held for quiet hours was enriched: %+v", held)
+	}
+	if broken.Body != "broken" || broken.Content != nil {
+		t.Errorf("failed detail should fall back to the title: %+v", broken)
+	}
+}
+
+func TestRegisterStage(t *testing.T) {
+	var ran []string
+	record := func(name string) Stage {
+		return Stage{Name: name, Run: func(ctx *FetchContext, items []*Item) ([]*Item, error) {
+			ran = append(ran, name)
+			return items, nil
+		}}
+	}
+
+	saved := stages
+	stages = []Stage{record(StageFilter), record(StageEnrich)}
+	t.Cleanup(func() { stages = saved })
+
+	for _, reg := range []struct{ after, name string }{
+		{StageFilter, "a"},
+		{StageFilter, "b"},
+		{StageEnrich, "c"},
+	} {
+		if err := RegisterStage(reg.after, record(reg.name)); err != nil {
+			t.Fatalf("RegisterStage(%s, %s): %v", reg.after, reg.name, err)
+		}
+	}
+	if err := RegisterStage(StageEnrich, record("a")); err == nil {
+		t.Error("a stage registered twice")
+	}
+	if err := RegisterStage("missing", record("d")); err == nil {
+		t.Error("a stage registered after a missing one")
+	}
+
+	// a failing stage is skipped, its output dropped
+	if err := RegisterStage("c", Stage{Name: "fails", Run: func(ctx *FetchContext, items []*Item) ([]*Item, error) {
+		ran = append(ran, "fails")
+		return nil, errors.New("boom")
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	items := []*Item{{}}
+	out := runStages(nil, items, StageFilter, newPipelineReport())
+	if want := []string{StageFilter, "b", "a", StageEnrich, "c", "fails"}; !slices.Equal(ran, want) {
+		t.Errorf("ran %v, want %v", ran, want)
+	}
+	if len(out) != 1 {
+		t.Errorf("got %d items after a failing stage, want 1", len(out))
+	}
+
+	// the quiet release starts at enrich
+	ran = nil
+	runStages(nil, items, StageEnrich, nil)
+	if want := []string{StageEnrich, "c", "fails"}; !slices.Equal(ran, want) {
+		t.Errorf("from enrich ran %v, want %v", ran, want)
+	}
+}
+
+func TestDeliverStageRecordsMatches(t *testing.T) {
+	useTestDB(t)
+	ctx := &FetchContext{Client: "test"}
+	sub := &model.UserSubscription{UserID: 1}
+	sub.ID = 3
+
+	batch := testBatch("test", []uint{1}, []string{"held", "released"})
+	fresh, err := diffStage(batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	held := testItem(sub, "held", "")
+	held.UserNoticeID = fresh[0][0].UserNoticeID
+	held.Hold = model.PendingDigest
+	released := testItem(sub, "released", "")
+	released.UserNoticeID = fresh[1][0].UserNoticeID
+	released.Hold = model.PendingDigest
+	released.Released = true
+	for _, it := range []*Item{held, released} {
+		it.Msg = newNoticeMessage(sub, 0, ctx.Client, it.Notice)
+	}
+
+	deliverStage(ctx, []*Item{held, released})
+
+	var un model.UserNotice
+	global.DB.First(&un, held.UserNoticeID)
+	if un.SubscriptionID != sub.ID || un.NoticeID == 0 {
+		t.Errorf("held match not recorded: %+v", un)
+	}
+	// recorded when it was held, not again
+	var again model.UserNotice
+	global.DB.First(&again, released.UserNoticeID)
+	if again.NoticeID != 0 {
+		t.Errorf("released match recorded again: %+v", again)
+	}
+
+	var pending int64
+	global.DB.Model(&model.PendingNotice{}).Count(&pending)
+	if pending != 2 {
+		t.Errorf("held %d notices, want 2", pending)
+	}
+}
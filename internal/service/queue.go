@@ -0,0 +1,384 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"noticat/internal/bridge"
+	"noticat/internal/filestore"
+	"noticat/internal/model"
+	"noticat/internal/notifier"
+	"noticat/pkg/global"
+)
+
+const (
+	pollInterval = 3 * time.Second
+	maxBackoff   = 6 * time.Hour
+)
+
+// ErrNotRetryable only dead jobs can be retried
+var ErrNotRetryable = errors.New("只能重试失败的投递")
+
+// wakeWorkers poll right away after something is queued
+var wakeWorkers = make(chan struct{}, 1)
+
+// recipient who gets a message and which UserNotices it covers
+type recipient struct {
+	UserID    uint
+	NoticeIDs []uint
+	Targets   []notifier.Target
+}
+
+// deliver queue msg for every target of rcpt, a notice counts as delivered
+// once one of its jobs is accepted by a channel
+func deliver(rcpt *recipient, msg *notifier.Message) {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("序列化通知失败: %v", err)
+		return
+	}
+
+	spoolDir := ""
+	if len(msg.Attachments) > 0 {
+		spoolDir = filepath.Dir(msg.Attachments[0])
+	}
+
+	ids := make([]string, 0, len(rcpt.NoticeIDs))
+	for _, id := range rcpt.NoticeIDs {
+		ids = append(ids, strconv.FormatUint(uint64(id), 10))
+	}
+
+	noticeHash := ""
+	if len(rcpt.NoticeIDs) == 1 && msg.URL != "" {
+		noticeHash = bridge.Notice{Title: msg.Title, URL: msg.URL}.ContentHash()
+	}
+	title := msg.Title
+	if title == "" {
+		title = msg.Subject
+	}
+
+	now := time.Now()
+	jobs := make([]model.DeliveryJob, 0, len(rcpt.Targets))
+	for _, t := range rcpt.Targets {
+		jobs = append(jobs, model.DeliveryJob{
+			UserID:         rcpt.UserID,
+			SubscriptionID: msg.SubscriptionID,
+			UserNoticeIDs:  strings.Join(ids, ","),
+			NoticeHash:     noticeHash,
+			Title:          title,
+			TargetID:       t.ID,
+			Channel:        t.Channel,
+			Address:        t.Address,
+			Payload:        string(payload),
+			SpoolDir:       spoolDir,
+			Status:         model.JobPending,
+			MaxAttempts:    8,
+			NextAttemptAt:  now,
+		})
+	}
+	if len(jobs) == 0 {
+		cleanupSpool(spoolDir)
+		return
+	}
+
+	if err := global.DB.Create(&jobs).Error; err != nil {
+		log.Printf("写入投递队列失败: %v", err)
+		cleanupSpool(spoolDir)
+		return
+	}
+
+	select {
+	case wakeWorkers <- struct{}{}:
+	default:
+	}
+}
+
+// StartDeliveryWorkers drain the delivery queue with NOTICAT_DELIVERY_WORKERS goroutines
+func StartDeliveryWorkers() {
+	n, err := strconv.Atoi(global.DeliveryWorkers)
+	if err != nil || n <= 0 {
+		n = 4
+	}
+
+	// jobs left by a crash
+	err = global.DB.Model(&model.DeliveryJob{}).
+		Where("status = ?", model.JobSending).
+		Update("status", model.JobPending).Error
+	if err != nil {
+		log.Printf("[Queue] 重置中断的投递失败: %v", err)
+	}
+
+	jobs := make(chan uint)
+	for i := 0; i < n; i++ {
+		go func() {
+			for id := range jobs {
+				runJob(id)
+			}
+		}()
+	}
+
+	go func() {
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+		for {
+			claimJobs(jobs, n*2)
+			select {
+			case <-ticker.C:
+			case <-wakeWorkers:
+			}
+		}
+	}()
+
+	log.Printf("[Queue] 🚀 投递队列已启动，worker 数量：%d", n)
+}
+
+// claimJobs mark due jobs as sending and hand them to the workers
+func claimJobs(jobs chan<- uint, limit int) {
+	for {
+		var ids []uint
+		err := global.DB.Model(&model.DeliveryJob{}).
+			Where("status = ? AND next_attempt_at <= ?", model.JobPending, time.Now()).
+			Order("next_attempt_at").
+			Limit(limit).
+			Pluck("id", &ids).Error
+		if err != nil {
+			log.Printf("[Queue] 查询投递队列失败: %v", err)
+			return
+		}
+		if len(ids) == 0 {
+			return
+		}
+
+		for _, id := range ids {
+			result := global.DB.Model(&model.DeliveryJob{}).
+				Where("id = ? AND status = ?", id, model.JobPending).
+				Update("status", model.JobSending)
+			if result.Error == nil && result.RowsAffected == 1 {
+				jobs <- id
+			}
+		}
+	}
+}
+
+func runJob(id uint) {
+	var job model.DeliveryJob
+	if err := global.DB.First(&job, id).Error; err != nil {
+		log.Printf("[Queue] 读取投递任务 %d 失败: %v", id, err)
+		return
+	}
+
+	var msg notifier.Message
+	if err := json.Unmarshal([]byte(job.Payload), &msg); err != nil {
+		finishJob(&job, nil, notifier.Permanent(fmt.Errorf("通知内容损坏: %w", err)))
+		return
+	}
+	// stable across the attempts of the job, unique across databases
+	msg.DeliveryID = uuid.NewSHA1(uuid.NameSpaceOID, fmt.Appendf(nil, "%d-%d", job.ID, job.CreatedAt.UnixNano())).String()
+
+	target := notifier.Target{ID: job.TargetID, Channel: job.Channel, Address: job.Address}
+	if job.TargetID != 0 {
+		var t model.NotifyTarget
+		if err := global.DB.First(&t, job.TargetID).Error; err != nil {
+			finishJob(&job, nil, notifier.Permanent(fmt.Errorf("通知渠道已删除")))
+			return
+		}
+		target.Address = t.Address
+		target.Secret = t.Secret
+	}
+
+	// the spool may be gone when a dead job is retried
+	restoreAttachments(&job, &msg)
+
+	err := safeSend(&target, &msg)
+	if err != nil {
+		log.Printf("[Queue] [%s] 第 %d 次投递失败: %v", target.Channel, job.Attempts+1, err)
+	}
+	finishJob(&job, &target, err)
+}
+
+// restoreAttachments put the attachments missing from the spool back from the
+// file store, the ones gone from it as well are named at the end of the body
+func restoreAttachments(job *model.DeliveryJob, msg *notifier.Message) {
+	var attachments, expired []string
+	for _, path := range msg.Attachments {
+		if _, err := os.Stat(path); err == nil || restoreAttachment(job, msg.Client, path) {
+			attachments = append(attachments, path)
+			continue
+		}
+		expired = append(expired, filepath.Base(path))
+	}
+	msg.Attachments = attachments
+
+	if len(expired) > 0 {
+		msg.Body += "\n\n———\n附件已过期：\n" + strings.Join(expired, "\n")
+	}
+}
+
+// restoreAttachment copy the stored file of the notice named like path back
+// to path. a zip bundle, or an attachment of a digest, was never stored
+func restoreAttachment(job *model.DeliveryJob, client string, path string) bool {
+	if job.NoticeHash == "" {
+		return false
+	}
+
+	var file model.NoticeFile
+	err := global.DB.
+		Where("client = ? AND notice_hash = ? AND name = ?", client, job.NoticeHash, filepath.Base(path)).
+		First(&file).Error
+	if err != nil || !filestore.Exists(file.FileHash) {
+		return false
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		log.Printf("[Queue] 恢复附件 %s 失败: %v", path, err)
+		return false
+	}
+	if err := filestore.CopyOut(file.FileHash, path); err != nil {
+		log.Printf("[Queue] 恢复附件 %s 失败: %v", path, err)
+		return false
+	}
+	touchStoredFile(file.FileHash)
+	return true
+}
+
+func safeSend(target *notifier.Target, msg *notifier.Message) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("发送时崩溃: %v", r)
+		}
+	}()
+	return notifier.Send(target, msg)
+}
+
+// finishJob record the result of one attempt
+func finishJob(job *model.DeliveryJob, target *notifier.Target, sendErr error) {
+	if target != nil {
+		recordTargetStatus(target, sendErr)
+	}
+
+	now := time.Now()
+	attempts := job.Attempts + 1
+	updates := map[string]any{"attempts": attempts}
+
+	attempt := model.DeliveryAttempt{JobID: job.ID, Attempt: attempts, Success: sendErr == nil}
+	if sendErr != nil {
+		attempt.Error = sendErr.Error()
+	}
+	if err := global.DB.Create(&attempt).Error; err != nil {
+		log.Printf("[Queue] 记录投递尝试失败: %v", err)
+	}
+
+	switch {
+	case sendErr == nil:
+		updates["status"] = model.JobSent
+		updates["last_error"] = ""
+		updates["delivered_at"] = now
+	case notifier.IsPermanent(sendErr) || attempts >= job.MaxAttempts:
+		updates["status"] = model.JobDead
+		updates["last_error"] = sendErr.Error()
+	default:
+		updates["status"] = model.JobPending
+		updates["last_error"] = sendErr.Error()
+		updates["next_attempt_at"] = now.Add(backoff(attempts))
+	}
+
+	if err := global.DB.Model(&model.DeliveryJob{}).Where("id = ?", job.ID).Updates(updates).Error; err != nil {
+		log.Printf("[Queue] 更新投递任务 %d 失败: %v", job.ID, err)
+		return
+	}
+
+	if sendErr == nil {
+		markDelivered(job.UserNoticeIDs, now)
+	}
+	if updates["status"] != model.JobPending {
+		cleanupSpool(job.SpoolDir)
+	}
+}
+
+// backoff 1m, 2m, 4m ... up to maxBackoff
+func backoff(attempts int) time.Duration {
+	d := time.Minute << (attempts - 1)
+	if d <= 0 || d > maxBackoff {
+		return maxBackoff
+	}
+	return d
+}
+
+func markDelivered(noticeIDs string, at time.Time) {
+	if noticeIDs == "" {
+		return
+	}
+	err := global.DB.Model(&model.UserNotice{}).
+		Where("id IN ? AND delivered_at IS NULL", strings.Split(noticeIDs, ",")).
+		Update("delivered_at", at).Error
+	if err != nil {
+		log.Printf("[Queue] 标记通知已送达失败: %v", err)
+	}
+}
+
+// cleanupSpool remove the attachments once no unfinished job needs them
+func cleanupSpool(dir string) {
+	if dir == "" {
+		return
+	}
+
+	var count int64
+	err := global.DB.Model(&model.DeliveryJob{}).
+		Where("spool_dir = ? AND status IN ?", dir, []string{model.JobPending, model.JobSending}).
+		Count(&count).Error
+	if err != nil || count > 0 {
+		return
+	}
+	os.RemoveAll(dir)
+}
+
+// RetryDelivery put a dead job of the user back to the queue
+func RetryDelivery(userID uint, jobID uint) error {
+	var job model.DeliveryJob
+	if err := global.DB.Where("id = ? AND user_id = ?", jobID, userID).First(&job).Error; err != nil {
+		return err
+	}
+	if job.Status != model.JobDead {
+		return ErrNotRetryable
+	}
+
+	err := global.DB.Model(&model.DeliveryJob{}).
+		Where("id = ? AND status = ?", job.ID, model.JobDead).
+		Updates(map[string]any{
+			"status":          model.JobPending,
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+		}).Error
+	if err != nil {
+		return err
+	}
+
+	select {
+	case wakeWorkers <- struct{}{}:
+	default:
+	}
+	return nil
+}
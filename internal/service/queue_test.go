@@ -0,0 +1,81 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"noticat/internal/bridge"
+	"noticat/internal/model"
+	"noticat/internal/notifier"
+	"noticat/pkg/global"
+)
+
+func TestRestoreAttachments(t *testing.T) {
+	useTestDB(t)
+	saved := global.FileStoreDir
+	global.FileStoreDir = t.TempDir()
+	t.Cleanup(func() { global.FileStoreDir = saved })
+
+	notice := bridge.Notice{Title: "奖学金公示", URL: "https://example.com/1"}
+	download := filepath.Join(t.TempDir(), "名单.pdf")
+	if err := os.WriteFile(download, []byte("stored"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := storeAttachment("test", notice.ContentHash(), "https://example.com/a", download); err != nil {
+		t.Fatal(err)
+	}
+
+	// the spool of the dead job was cleaned, but one file is still there
+	spool := filepath.Join(t.TempDir(), "noticat_1")
+	kept := filepath.Join(t.TempDir(), "kept.txt")
+	if err := os.WriteFile(kept, []byte("kept"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	job := &model.DeliveryJob{NoticeHash: notice.ContentHash(), SpoolDir: spool}
+	msg := &notifier.Message{
+		Client: "test",
+		Body:   "正文",
+		Attachments: []string{
+			filepath.Join(spool, "名单.pdf"),
+			filepath.Join(spool, "attachments-1.zip"),
+			kept,
+		},
+	}
+
+	restoreAttachments(job, msg)
+
+	want := []string{filepath.Join(spool, "名单.pdf"), kept}
+	if !slices.Equal(msg.Attachments, want) {
+		t.Fatalf("attachments = %v, want %v", msg.Attachments, want)
+	}
+	if data, err := os.ReadFile(want[0]); err != nil || string(data) != "stored" {
+		t.Errorf("restored file = %q (%v)", data, err)
+	}
+	if !strings.Contains(msg.Body, "附件已过期") || !strings.Contains(msg.Body, "attachments-1.zip") {
+		t.Errorf("body does not name the expired attachment: %q", msg.Body)
+	}
+
+	// a digest has no notice to look the files up by
+	digest := &notifier.Message{Client: "test", Attachments: []string{filepath.Join(t.TempDir(), "名单.pdf")}}
+	restoreAttachments(&model.DeliveryJob{}, digest)
+	if len(digest.Attachments) != 0 || !strings.Contains(digest.Body, "名单.pdf") {
+		t.Errorf("digest attachments = %v, body %q", digest.Attachments, digest.Body)
+	}
+}
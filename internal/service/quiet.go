@@ -0,0 +1,197 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"noticat/internal/bridge"
+	"noticat/internal/model"
+	"noticat/pkg/common"
+	"noticat/pkg/global"
+)
+
+var (
+	serverLocOnce sync.Once
+	serverLoc     *time.Location
+)
+
+// ServerLocation NOTICAT_TIMEZONE, local time if it is invalid
+func ServerLocation() *time.Location {
+	serverLocOnce.Do(func() {
+		loc, err := time.LoadLocation(global.TimeZone)
+		if err != nil {
+			log.Printf("无法加载时区 %s: %v, 使用本地时区", global.TimeZone, err)
+			loc = time.Local
+		}
+		serverLoc = loc
+	})
+	return serverLoc
+}
+
+// inQuietHours whether now is inside the quiet hours of user (in the timezone of user)
+func inQuietHours(user *model.User, now time.Time) bool {
+	local := now.In(user.Location(ServerLocation()))
+	minute := local.Hour()*60 + local.Minute()
+
+	// today's window
+	if start, end, ok := parseWindow(user.QuietWindow(local.Weekday())); ok {
+		if start < end && minute >= start && minute < end {
+			return true
+		}
+		if start > end && minute >= start {
+			return true
+		}
+	}
+
+	// the part after midnight of yesterday's window
+	yesterday := local.AddDate(0, 0, -1).Weekday()
+	if start, end, ok := parseWindow(user.QuietWindow(yesterday)); ok {
+		if start > end && minute < end {
+			return true
+		}
+	}
+	return false
+}
+
+// parseWindow "HH:MM" pair -> minutes of the day
+func parseWindow(start, end string) (int, int, bool) {
+	if start == "" || end == "" {
+		return 0, 0, false
+	}
+	s, err1 := time.Parse("15:04", start)
+	e, err2 := time.Parse("15:04", end)
+	if err1 != nil || err2 != nil || start == end {
+		return 0, 0, false
+	}
+	return s.Hour()*60 + s.Minute(), e.Hour()*60 + e.Minute(), true
+}
+
+// ReleaseQuietNotices deliver the notices held by quiet hours which have ended,
+// called by the scheduler every minute
+func ReleaseQuietNotices(now time.Time) {
+	var userIDs []uint
+	err := global.DB.Model(&model.PendingNotice{}).
+		Where("reason = ?", model.PendingQuiet).
+		Distinct().
+		Pluck("user_id", &userIDs).Error
+	if err != nil {
+		log.Printf("[Quiet] 查询暂存通知失败: %v", err)
+		return
+	}
+
+	for _, uid := range userIDs {
+		var user model.User
+		if err := global.DB.First(&user, uid).Error; err != nil {
+			log.Printf("[Quiet] 查询用户 %d 失败: %v", uid, err)
+			continue
+		}
+		if inQuietHours(&user, now) {
+			continue
+		}
+		releaseUserNotices(&user)
+	}
+}
+
+func releaseUserNotices(user *model.User) {
+	var pendings []model.PendingNotice
+	err := global.DB.Where("user_id = ? AND reason = ?", user.ID, model.PendingQuiet).Order("id").Find(&pendings).Error
+	if err != nil {
+		log.Printf("[Quiet] 查询用户 %d 的暂存通知失败: %v", user.ID, err)
+		return
+	}
+
+	pendings = claimPending(pendings)
+
+	subs := make(map[uint]*model.UserSubscription)
+	for _, p := range pendings {
+		sub, ok := subs[p.SubscriptionID]
+		if !ok {
+			var s model.UserSubscription
+			if err := global.DB.Preload("User").Preload("Task").First(&s, p.SubscriptionID).Error; err != nil {
+				// the subscription is gone
+				sub = nil
+			} else {
+				sub = &s
+			}
+			subs[p.SubscriptionID] = sub
+		}
+
+		if sub != nil {
+			notice := bridge.Notice{Title: p.Title, URL: p.URL, Date: p.Date}
+			msg := newNoticeMessage(sub, 0, p.Client, notice)
+			if p.Diff != "" {
+				msg.Event = "updated"
+				msg.Subject = "[NotiCat][更新]" + common.ShortenTitle(notice.Title)
+			}
+
+			fetchCtx, err := ParseFetchContext(sub.Task.Client, sub.Task.Credentials, sub.Task.Extra)
+			if err != nil {
+				log.Printf("[Quiet] 订阅 %d 的任务配置损坏: %v", sub.ID, err)
+				msg.Body = withDiff(p.Diff, notice.Title)
+				deliver(&recipient{
+					UserID:    user.ID,
+					NoticeIDs: []uint{p.UserNoticeID},
+					Targets:   subscriptionTargets(sub),
+				}, msg)
+			} else {
+				// filtered already, the rest of the pipeline still applies
+				items := []*Item{{Notice: notice, Subscriber: &Subscriber{Sub: sub}, UserNoticeID: p.UserNoticeID, Msg: msg, Released: true}}
+				items = runStages(fetchCtx, items, StageEnrich, nil)
+				for _, it := range items {
+					it.Body = withDiff(p.Diff, it.Body)
+				}
+				deliverStage(fetchCtx, items)
+			}
+		}
+	}
+	log.Printf("[Quiet] 用户 %d 的免打扰时段结束，已发送 %d 条暂存通知", user.ID, len(pendings))
+}
+
+// withDiff the body of a held update: its diff above the detail
+func withDiff(diff string, body string) string {
+	if diff == "" {
+		return body
+	}
+	return diff + "<hr>" + body
+}
+
+// claimPending delete the rows before working on them, so a run overlapping
+// this one does not send them again. the rows another run deleted first are
+// left to it
+func claimPending(pendings []model.PendingNotice) []model.PendingNotice {
+	var claimed []model.PendingNotice
+	err := global.DB.Transaction(func(tx *gorm.DB) error {
+		for _, p := range pendings {
+			result := tx.Unscoped().Delete(&model.PendingNotice{}, p.ID)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 1 {
+				claimed = append(claimed, p)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("[Pending] 认领暂存通知失败: %v", err)
+		return nil
+	}
+	return claimed
+}
@@ -0,0 +1,114 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"strings"
+	"time"
+
+	"noticat/internal/search"
+)
+
+const snippetWidth = 120
+
+type SearchQuery struct {
+	Query  string
+	Client string
+	// range of the notice date, the received time for the notices without
+	// one, zero: open
+	From     time.Time
+	To       time.Time
+	Page     int
+	PageSize int
+}
+
+// noticeTime the parsed date of the notice, the received time if it has none
+const noticeTime = "julianday(COALESCE(n.published_at, un.created_at))"
+
+type SearchResult struct {
+	NoticeItem
+	// title and a piece of the detail, html escaped with the hits in <mark>
+	Highlight string
+	Snippet   string
+}
+
+// likePattern %term% with the wildcards of term escaped
+func likePattern(term string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return "%" + r.Replace(term) + "%"
+}
+
+// SearchNotices the notices of the user matching q.Query, best match first with
+// fts5, newest first with the LIKE fallback
+func SearchNotices(userID uint, q *SearchQuery) ([]SearchResult, int64, error) {
+	terms := search.Terms(q.Query)
+	if len(terms) == 0 {
+		return nil, 0, nil
+	}
+
+	// the body makes the snippet
+	query := userNoticeItems(userID, true)
+	if search.Enabled() {
+		match := search.MatchQuery(q.Query)
+		if match == "" {
+			// only punctuation, nothing in the index can match
+			return nil, 0, nil
+		}
+		query = query.
+			Joins("JOIN "+search.Table+" ON "+search.Table+".rowid = n.id").
+			Where(search.Table+" MATCH ?", match).
+			Order("bm25(" + search.Table + ")")
+	} else {
+		for _, term := range terms {
+			pattern := likePattern(term)
+			query = query.Where(`(n.title LIKE ? ESCAPE '\' OR n.body LIKE ? ESCAPE '\')`, pattern, pattern)
+		}
+	}
+	query = query.Order("un.id DESC")
+
+	if q.Client != "" {
+		query = query.Where("n.client = ?", q.Client)
+	}
+	// the times are stored with the offset of their zone, compare them as instants
+	if !q.From.IsZero() {
+		query = query.Where(noticeTime+" >= julianday(?)", q.From.UTC())
+	}
+	if !q.To.IsZero() {
+		query = query.Where(noticeTime+" < julianday(?)", q.To.UTC())
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var items []NoticeItem
+	err := query.Offset((q.Page - 1) * q.PageSize).Limit(q.PageSize).Scan(&items).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	results := make([]SearchResult, 0, len(items))
+	for _, it := range items {
+		r := SearchResult{
+			NoticeItem: it,
+			Highlight:  search.Snippet(it.Title, terms, 0),
+			Snippet:    search.Snippet(search.PlainText(it.Body), terms, snippetWidth),
+		}
+		r.Body = ""
+		results = append(results, r)
+	}
+	return results, total, nil
+}
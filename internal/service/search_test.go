@@ -0,0 +1,81 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"noticat/internal/model"
+	"noticat/pkg/global"
+)
+
+func TestSearchNoticesDateRange(t *testing.T) {
+	useTestDB(t)
+	cst := time.FixedZone("CST", 8*3600)
+	day := func(d int) *time.Time {
+		t := time.Date(2026, 3, d, 0, 0, 0, 0, cst)
+		return &t
+	}
+	// all received long after they were published, in another zone
+	received := time.Date(2026, 3, 20, 2, 0, 0, 0, time.UTC)
+
+	for i, n := range []struct {
+		title     string
+		published *time.Time
+	}{
+		{"奖学金 三月一日", day(1)},
+		{"奖学金 三月五日", day(5)},
+		{"奖学金 三月十日", day(10)},
+		{"奖学金 无日期", nil},
+	} {
+		notice := model.Notice{Client: "test", ContentHash: n.title, Title: n.title, PublishedAt: n.published}
+		if err := global.DB.Create(&notice).Error; err != nil {
+			t.Fatal(err)
+		}
+		un := model.UserNotice{UserID: 1, SubscriptionID: 1, Client: "test", ContentHash: n.title, NoticeID: notice.ID}
+		un.CreatedAt = received.Add(time.Duration(i) * time.Minute)
+		if err := global.DB.Create(&un).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		name     string
+		from, to time.Time
+		want     []string
+	}{
+		{"notice dates", *day(2), *day(11), []string{"奖学金 三月十日", "奖学金 三月五日"}},
+		// 03-05 00:00 CST is 03-04 16:00 UTC
+		{"inclusive from in another zone", time.Date(2026, 3, 4, 16, 0, 0, 0, time.UTC), *day(6), []string{"奖学金 三月五日"}},
+		{"exclusive to", *day(1), *day(5), []string{"奖学金 三月一日"}},
+		{"no date falls back to received", received, time.Time{}, []string{"奖学金 无日期"}},
+		{"open", time.Time{}, time.Time{}, []string{"奖学金 无日期", "奖学金 三月十日", "奖学金 三月五日", "奖学金 三月一日"}},
+	}
+	for _, tc := range cases {
+		results, total, err := SearchNotices(1, &SearchQuery{Query: "奖学金", From: tc.from, To: tc.to, Page: 1, PageSize: 20})
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		var titles []string
+		for _, r := range results {
+			titles = append(titles, r.Title)
+		}
+		if !slices.Equal(titles, tc.want) || total != int64(len(tc.want)) {
+			t.Errorf("%s: got %v (total %d), want %v", tc.name, titles, total, tc.want)
+		}
+	}
+}
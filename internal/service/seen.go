@@ -0,0 +1,84 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"noticat/pkg/global"
+)
+
+// rows per INSERT, 8 variables each stays far below the sqlite limit
+const seenBatchSize = 500
+
+// seenRow a UserNotice marking that a user has seen a notice
+type seenRow struct {
+	ID          uint
+	UserID      uint
+	ContentHash string
+}
+
+func seenKey(userID uint, hash string) string {
+	return fmt.Sprintf("%d\n%s", userID, hash)
+}
+
+// knownNotices which of hashes the users have seen already, keyed by seenKey
+func knownNotices(client string, userIDs []uint, hashes []string) (map[string]bool, error) {
+	var rows []seenRow
+	err := global.DB.Table("user_notices").
+		Select("user_id, content_hash").
+		Where("client = ? AND user_id IN ? AND content_hash IN ?", client, userIDs, hashes).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]bool, len(rows))
+	for _, r := range rows {
+		known[seenKey(r.UserID, r.ContentHash)] = true
+	}
+	return known, nil
+}
+
+// insertSeen mark rows as seen in batches. a row someone else inserted in the
+// meantime is skipped, only the rows inserted here come back, with their ids
+func insertSeen(client string, rows []seenRow) ([]seenRow, error) {
+	var inserted []seenRow
+	now := time.Now()
+	for start := 0; start < len(rows); start += seenBatchSize {
+		batch := rows[start:min(start+seenBatchSize, len(rows))]
+
+		values := make([]string, 0, len(batch))
+		args := make([]any, 0, len(batch)*8)
+		for _, r := range batch {
+			values = append(values, "(?, ?, ?, ?, ?, ?, ?, ?)")
+			args = append(args, now, now, r.UserID, client, r.ContentHash, 0, 0, false)
+		}
+
+		var out []seenRow
+		err := global.DB.Raw(`INSERT INTO user_notices
+			(created_at, updated_at, user_id, client, content_hash, subscription_id, notice_id, starred)
+			VALUES `+strings.Join(values, ", ")+`
+			ON CONFLICT DO NOTHING
+			RETURNING id, user_id, content_hash`, args...).Scan(&out).Error
+		if err != nil {
+			return inserted, err
+		}
+		inserted = append(inserted, out...)
+	}
+	return inserted, nil
+}
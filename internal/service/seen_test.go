@@ -0,0 +1,233 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"noticat/internal/bridge"
+	"noticat/internal/model"
+	"noticat/pkg/global"
+)
+
+// useTestDB point global.DB to a fresh sqlite file for the test
+func useTestDB(tb testing.TB) {
+	tb.Helper()
+	path := filepath.Join(tb.TempDir(), "noticat.db")
+	db, err := gorm.Open(sqlite.Open(path+"?_journal_mode=WAL"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if err := db.AutoMigrate(&model.User{}, &model.UserSubscription{}, &model.SubscriptionFilter{}, &model.UserNotice{}, &model.Notice{}, &model.PendingNotice{}, &model.DeliveryJob{}, &model.NotifyTarget{}, &model.StoredFile{}, &model.NoticeFile{}); err != nil {
+		tb.Fatal(err)
+	}
+
+	prev := global.DB
+	global.DB = db
+	tb.Cleanup(func() {
+		global.DB = prev
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+}
+
+func testBatch(client string, userIDs []uint, titles []string) *Batch {
+	batch := &Batch{Ctx: &FetchContext{Client: client}}
+	for _, title := range titles {
+		batch.Notices = append(batch.Notices, bridge.Notice{Title: title, URL: "https://example.com/" + title})
+	}
+	for _, id := range userIDs {
+		batch.Subscribers = append(batch.Subscribers, &Subscriber{Sub: &model.UserSubscription{UserID: id}})
+	}
+	return batch
+}
+
+// firstOrCreateDiff the per-row diff diffStage replaced
+func firstOrCreateDiff(batch *Batch) ([]string, error) {
+	var fresh []string
+	for _, notice := range batch.Notices {
+		for _, s := range batch.Subscribers {
+			un := model.UserNotice{UserID: s.Sub.UserID, Client: batch.Ctx.Client, ContentHash: notice.ContentHash()}
+			result := global.DB.Where(&un).FirstOrCreate(&un)
+			if result.Error != nil {
+				return nil, result.Error
+			}
+			if result.RowsAffected > 0 {
+				fresh = append(fresh, seenKey(un.UserID, un.ContentHash))
+			}
+		}
+	}
+	return fresh, nil
+}
+
+func diffKeys(tb testing.TB, batch *Batch) []string {
+	tb.Helper()
+	items, err := diffStage(batch)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	var keys []string
+	for i, notice := range items {
+		for _, it := range notice {
+			if it.Notice.ContentHash() != batch.Notices[i].ContentHash() {
+				tb.Errorf("item of notice %d has the wrong notice", i)
+			}
+			if it.UserNoticeID == 0 {
+				tb.Errorf("item of notice %d has no UserNotice id", i)
+			}
+			keys = append(keys, seenKey(it.Subscriber.Sub.UserID, it.Notice.ContentHash()))
+		}
+	}
+	return keys
+}
+
+func TestDiffStageMatchesFirstOrCreate(t *testing.T) {
+	tests := []struct {
+		name   string
+		users  []uint
+		titles []string
+		// seen before the run: user -> titles
+		seen map[uint][]string
+		// other clients do not count
+		seenElsewhere map[uint][]string
+		// new (user, hash) pairs
+		want int
+	}{
+		{name: "all new", users: []uint{1, 2}, titles: []string{"a", "b", "c"}, want: 6},
+		{name: "no subscribers", titles: []string{"a"}, want: 0},
+		{name: "subscribed twice", users: []uint{1, 1, 2}, titles: []string{"a", "b"}, want: 4},
+		{name: "listed twice", users: []uint{1, 2}, titles: []string{"a", "b", "a"}, want: 4},
+		{
+			name:   "some seen",
+			users:  []uint{1, 2, 3},
+			titles: []string{"a", "b", "c"},
+			seen:   map[uint][]string{1: {"a", "b", "c"}, 2: {"b"}},
+			want:   5,
+		},
+		{
+			name:          "seen on another client",
+			users:         []uint{1},
+			titles:        []string{"a"},
+			seenElsewhere: map[uint][]string{1: {"a"}},
+			want:          1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var results [2][]string
+			for run, diff := range []func(*Batch) ([]string, error){
+				func(b *Batch) ([]string, error) { return diffKeys(t, b), nil },
+				firstOrCreateDiff,
+			} {
+				useTestDB(t)
+				for client, seen := range map[string]map[uint][]string{"bupt": tt.seen, "other": tt.seenElsewhere} {
+					for userID, titles := range seen {
+						if _, err := firstOrCreateDiff(testBatch(client, []uint{userID}, titles)); err != nil {
+							t.Fatal(err)
+						}
+					}
+				}
+
+				got, err := diff(testBatch("bupt", tt.users, tt.titles))
+				if err != nil {
+					t.Fatal(err)
+				}
+				slices.Sort(got)
+				results[run] = got
+			}
+
+			if len(results[0]) != tt.want {
+				t.Errorf("diffStage found %d new, want %d", len(results[0]), tt.want)
+			}
+			if !slices.Equal(results[0], results[1]) {
+				t.Errorf("diffStage %q, FirstOrCreate %q", results[0], results[1])
+			}
+
+			// a second run finds nothing new
+			if again := diffKeys(t, testBatch("bupt", tt.users, tt.titles)); len(again) != 0 {
+				t.Errorf("second run found %q", again)
+			}
+		})
+	}
+}
+
+const (
+	benchSubscribers = 50
+	benchNotices     = 100
+)
+
+func benchBatch() *Batch {
+	users := make([]uint, benchSubscribers)
+	for i := range users {
+		users[i] = uint(i + 1)
+	}
+	titles := make([]string, benchNotices)
+	for i := range titles {
+		titles[i] = fmt.Sprintf("notice-%d", i)
+	}
+	return testBatch("bupt", users, titles)
+}
+
+// benchmarkDiff the first run of a task inserts every row, the steady runs
+// after it find nothing new
+func benchmarkDiff(b *testing.B, diff func(*Batch) error) {
+	b.Run("first", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			useTestDB(b)
+			batch := benchBatch()
+			b.StartTimer()
+			if err := diff(batch); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("steady", func(b *testing.B) {
+		useTestDB(b)
+		batch := benchBatch()
+		if err := diff(batch); err != nil {
+			b.Fatal(err)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if err := diff(batch); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkDiffStage(b *testing.B) {
+	benchmarkDiff(b, func(batch *Batch) error {
+		_, err := diffStage(batch)
+		return err
+	})
+}
+
+func BenchmarkFirstOrCreate(b *testing.B) {
+	benchmarkDiff(b, func(batch *Batch) error {
+		_, err := firstOrCreateDiff(batch)
+		return err
+	})
+}
@@ -0,0 +1,162 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"gorm.io/gorm"
+
+	"noticat/internal/model"
+	"noticat/pkg/global"
+)
+
+var ErrSubscriptionNotFound = errors.New("订阅不存在")
+
+// DeleteSubscription remove the subscription with its filters, targets and
+// pending notices, the task goes too when nobody subscribes it anymore
+func DeleteSubscription(userID uint, subscriptionID uint) error {
+	return global.DB.Transaction(func(tx *gorm.DB) error {
+		var sub model.UserSubscription
+		if err := tx.Where("id = ? AND user_id = ?", subscriptionID, userID).First(&sub).Error; err != nil {
+			log.Printf("错误: %v", err)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrSubscriptionNotFound
+			}
+			return fmt.Errorf("无法查询订阅")
+		}
+		taskID := sub.TaskID
+
+		// delete filters
+		if err := tx.Unscoped().Where("subscription_id = ?", sub.ID).Delete(&model.SubscriptionFilter{}).Error; err != nil {
+			log.Printf("错误: %v", err)
+			return fmt.Errorf("无法删除filters")
+		}
+
+		// delete notify targets of this subscription
+		if err := tx.Where("subscription_id = ?", sub.ID).Delete(&model.NotifyTarget{}).Error; err != nil {
+			log.Printf("错误: %v", err)
+			return fmt.Errorf("无法删除通知渠道")
+		}
+
+		// delete address routes
+		if err := tx.Where("subscription_id = ?", sub.ID).Delete(&model.SubscriptionAddress{}).Error; err != nil {
+			log.Printf("错误: %v", err)
+			return fmt.Errorf("无法删除订阅地址")
+		}
+
+		// drop the notices waiting for digest
+		if err := tx.Unscoped().Where("subscription_id = ?", sub.ID).Delete(&model.PendingNotice{}).Error; err != nil {
+			log.Printf("错误: %v", err)
+			return fmt.Errorf("无法删除待发摘要")
+		}
+
+		// delete subscription
+		if err := tx.Delete(&sub).Error; err != nil {
+			log.Printf("错误: %v", err)
+			return fmt.Errorf("无法删除订阅")
+		}
+
+		var count int64
+		if err := tx.Model(&model.UserSubscription{}).Where("task_id = ?", taskID).Count(&count).Error; err != nil {
+			log.Printf("错误: %v", err)
+			return fmt.Errorf("无法统计任务订阅数")
+		}
+
+		if count == 0 {
+			// delete task
+			if err := tx.Delete(&model.FetchTask{}, taskID).Error; err != nil {
+				log.Printf("错误: %v", err)
+				return fmt.Errorf("无法删除任务")
+			}
+			log.Printf("任务 %d 已无订阅者，已彻底移除", taskID)
+		}
+
+		return nil
+	})
+}
+
+// SetSubscriptionPaused pause or resume a subscription
+func SetSubscriptionPaused(userID uint, subscriptionID uint, paused bool) error {
+	result := global.DB.Model(&model.UserSubscription{}).
+		Where("id = ? AND user_id = ?", subscriptionID, userID).
+		Update("paused", paused)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrSubscriptionNotFound
+	}
+	return nil
+}
+
+// SetAllSubscriptionsPaused pause or resume every subscription of the user
+func SetAllSubscriptionsPaused(userID uint, paused bool) error {
+	return global.DB.Model(&model.UserSubscription{}).
+		Where("user_id = ?", userID).
+		Update("paused", paused).Error
+}
+
+// SetSubscriptionMaxAge skip notices published more than days ago, 0: keep all
+func SetSubscriptionMaxAge(userID uint, subscriptionID uint, days int) error {
+	result := global.DB.Model(&model.UserSubscription{}).
+		Where("id = ? AND user_id = ?", subscriptionID, userID).
+		Update("max_age_days", days)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrSubscriptionNotFound
+	}
+	return nil
+}
+
+// SetSubscriptionNotifyUpdates turn the notifications of edited notices on or off
+func SetSubscriptionNotifyUpdates(userID uint, subscriptionID uint, enabled bool) error {
+	result := global.DB.Model(&model.UserSubscription{}).
+		Where("id = ? AND user_id = ?", subscriptionID, userID).
+		Update("notify_updates", enabled)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrSubscriptionNotFound
+	}
+	return nil
+}
+
+// SetFilterMuted mute or unmute one filter of a subscription
+func SetFilterMuted(userID uint, subscriptionID uint, filterID uint, muted bool) error {
+	var sub model.UserSubscription
+	if err := global.DB.Where("id = ? AND user_id = ?", subscriptionID, userID).First(&sub).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrSubscriptionNotFound
+		}
+		return err
+	}
+
+	result := global.DB.Model(&model.SubscriptionFilter{}).
+		Where("id = ? AND subscription_id = ?", filterID, sub.ID).
+		Update("muted", muted)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"noticat/internal/notifier"
+	"noticat/pkg/global"
+)
+
+const unsubscribeTTL = 30 * 24 * time.Hour
+
+var ErrInvalidUnsubscribe = errors.New("退订链接无效或已过期")
+
+// UnsubscribeClaims what an unsubscribe link may touch, FilterID 0: no filter.
+// SubscriptionID 0: every subscription of the user, for digests mixing several
+type UnsubscribeClaims struct {
+	UserID         uint `json:"uid"`
+	SubscriptionID uint `json:"sid"`
+	FilterID       uint `json:"fid,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// unsubscribeKey not the login key, so a link never works as a login token
+func unsubscribeKey() []byte {
+	return append([]byte("unsubscribe:"), global.JwtSecret...)
+}
+
+func NewUnsubscribeToken(userID, subscriptionID, filterID uint) (string, error) {
+	claims := UnsubscribeClaims{
+		UserID:         userID,
+		SubscriptionID: subscriptionID,
+		FilterID:       filterID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "unsubscribe",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(unsubscribeTTL)),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(unsubscribeKey())
+}
+
+func ParseUnsubscribeToken(tokenString string) (*UnsubscribeClaims, error) {
+	var claims UnsubscribeClaims
+	token, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (any, error) {
+		return unsubscribeKey(), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithSubject("unsubscribe"))
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidUnsubscribe
+	}
+	return &claims, nil
+}
+
+// attachUnsubscribe put the unsubscribe link of the subscription on the message
+func attachUnsubscribe(msg *notifier.Message, userID, subscriptionID, filterID uint) {
+	token, err := NewUnsubscribeToken(userID, subscriptionID, filterID)
+	if err != nil {
+		return
+	}
+	msg.UnsubscribeURL = fmt.Sprintf("%s/unsubscribe/%s", global.PublicURL, token)
+}
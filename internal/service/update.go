@@ -0,0 +1,206 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"log"
+	"strings"
+	"time"
+
+	"noticat/internal/bridge"
+	"noticat/internal/model"
+	"noticat/internal/notifier"
+	"noticat/internal/stream"
+	"noticat/pkg/common"
+	"noticat/pkg/global"
+)
+
+// at most this many notices of a task are fetched again per run
+const recheckLimit = 10
+
+// detailText what an edit is judged on: the text of the detail and the
+// names of its attachments
+func detailText(body string, attachments []bridge.Attachment) string {
+	text := strings.TrimSpace(common.HTMLToText(body))
+	if len(attachments) > 0 {
+		var b strings.Builder
+		b.WriteString(text)
+		b.WriteString("\n\n附件：")
+		for _, a := range attachments {
+			b.WriteString("\n- " + strings.TrimSpace(a.Title))
+		}
+		text = strings.TrimSpace(b.String())
+	}
+	return text
+}
+
+func textFingerprint(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// recheckStage fetch the detail of delivered notices of the batch again once
+// NOTICAT_UPDATE_CHECK_MINUTES passed, an edit is found by saveNoticeDetail.
+// only notices someone wants updates of are fetched, returns how many
+func recheckStage(batch *Batch) int {
+	interval := time.Duration(atoiOr(global.UpdateCheckMinutes, 60)) * time.Minute
+	if interval <= 0 {
+		return 0
+	}
+
+	byHash := make(map[string]bridge.Notice, len(batch.Notices))
+	hashes := make([]string, 0, len(batch.Notices))
+	for _, notice := range batch.Notices {
+		hash := notice.ContentHash()
+		byHash[hash] = notice
+		hashes = append(hashes, hash)
+	}
+
+	now := time.Now()
+	var due []model.Notice
+	err := global.DB.
+		Where("client = ? AND content_hash IN ? AND detail_at IS NOT NULL", batch.Ctx.Client, hashes).
+		Where("checked_at IS NULL OR checked_at < ?", now.Add(-interval)).
+		Where(`id IN (SELECT un.notice_id FROM user_notices AS un
+			JOIN user_subscriptions AS s ON s.id = un.subscription_id AND s.deleted_at IS NULL
+			WHERE un.delivered_at IS NOT NULL AND un.deleted_at IS NULL AND s.notify_updates = ? AND s.paused = ?)`, true, false).
+		Order("checked_at").
+		Limit(recheckLimit).
+		Find(&due).Error
+	if err != nil {
+		log.Printf("查询待复查通知失败: %v", err)
+		return 0
+	}
+
+	for _, n := range due {
+		// a failed fetch waits for the next interval as well
+		global.DB.Model(&model.Notice{}).Where("id = ?", n.ID).Update("checked_at", now)
+		// a cached copy fetched minutes ago would hide the edit
+		if _, err := refreshNoticeContent(batch.Ctx, byHash[n.ContentHash]); err != nil {
+			log.Printf("复查通知 %d 失败: %v", n.ID, err)
+		}
+	}
+	return len(due)
+}
+
+// notifyNoticeUpdate tell the subscribers who got notice n that it was edited,
+// with a diff of the text. like a new notice it waits for the digest or the end
+// of quiet hours, unless the subscription is urgent
+func notifyNoticeUpdate(client string, n *model.Notice, previous string, content *noticeContent) {
+	var rows []struct {
+		UserNoticeID   uint
+		SubscriptionID uint
+	}
+	err := global.DB.Table("user_notices AS un").
+		Select("un.id AS user_notice_id, un.subscription_id").
+		Joins("JOIN user_subscriptions AS s ON s.id = un.subscription_id AND s.deleted_at IS NULL").
+		Where("un.notice_id = ? AND un.delivered_at IS NOT NULL AND un.deleted_at IS NULL", n.ID).
+		Where("s.notify_updates = ? AND s.paused = ?", true, false).
+		Scan(&rows).Error
+	if err != nil {
+		log.Printf("查询通知 %d 的订阅者失败: %v", n.ID, err)
+		return
+	}
+	if len(rows) == 0 {
+		return
+	}
+
+	notice := bridge.Notice{Title: n.Title, URL: n.URL, Date: n.Date, Deadline: n.Deadline}
+	diff := renderTextDiff(previous, detailText(content.Body, content.Attachments))
+
+	for _, r := range rows {
+		var sub model.UserSubscription
+		if err := global.DB.Preload("User").First(&sub, r.SubscriptionID).Error; err != nil {
+			continue
+		}
+
+		msg := newNoticeMessage(&sub, 0, client, notice)
+		msg.Event = "updated"
+		msg.Subject = "[NotiCat][更新]" + common.ShortenTitle(notice.Title)
+		msg.Body = diff + "<hr>" + content.Body
+		for i, a := range content.Attachments {
+			msg.Links = append(msg.Links, notifier.Link{Title: a.Title, URL: a.URL})
+			if f := content.Files[i]; f.Hash != "" {
+				linkStoredFile(msg, i, f.Hash, f.Name)
+			}
+		}
+
+		stream.Publish(sub.UserID, stream.Event{
+			Type:           "updated",
+			Title:          msg.Title,
+			URL:            msg.URL,
+			Date:           msg.Date,
+			Client:         msg.Client,
+			SubscriptionID: msg.SubscriptionID,
+		})
+
+		// back to unread in the inbox
+		global.DB.Model(&model.UserNotice{}).Where("id = ?", r.UserNoticeID).Update("read_at", nil)
+
+		if reason := updateHold(&sub, time.Now()); reason != "" {
+			holdUpdate(&sub, r.UserNoticeID, client, notice, reason, diff)
+			continue
+		}
+		deliver(&recipient{
+			UserID:    sub.UserID,
+			NoticeIDs: []uint{r.UserNoticeID},
+			Targets:   subscriptionTargets(&sub),
+		}, msg)
+	}
+	log.Printf("通知 %d 内容有更新，已提醒 %d 个订阅", n.ID, len(rows))
+}
+
+// updateHold why an update of sub waits, "": send it now
+func updateHold(sub *model.UserSubscription, now time.Time) string {
+	if sub.Urgent {
+		return ""
+	}
+	if mode, _, _ := sub.Delivery(); mode != model.DeliveryImmediate {
+		return model.PendingDigest
+	}
+	if inQuietHours(&sub.User, now) {
+		return model.PendingQuiet
+	}
+	return ""
+}
+
+// renderTextDiff the changed lines of the text as html, removed lines in red
+// and added lines in green
+func renderTextDiff(previous, current string) string {
+	var b strings.Builder
+	b.WriteString("<p><b>通知内容有更新：</b></p>\n")
+	b.WriteString(`<pre style="white-space:pre-wrap;font-family:inherit">`)
+	for _, d := range common.ChangedLines(common.LineDiff(previous, current), 1) {
+		if d == nil {
+			b.WriteString("…\n")
+			continue
+		}
+		line := html.EscapeString(d.Text)
+		switch d.Op {
+		case common.DiffDelete:
+			fmt.Fprintf(&b, "<span style=\"color:#b31d28;text-decoration:line-through\">- %s</span>\n", line)
+		case common.DiffInsert:
+			fmt.Fprintf(&b, "<span style=\"color:#22863a\">+ %s</span>\n", line)
+		default:
+			fmt.Fprintf(&b, "  %s\n", line)
+		}
+	}
+	b.WriteString("</pre>")
+	return b.String()
+}
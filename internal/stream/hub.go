@@ -0,0 +1,135 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package stream live notices for the sse endpoint
+package stream
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// replayLimit events kept per user for Last-Event-ID resume
+	replayLimit = 100
+	replayTTL   = 24 * time.Hour
+	// subscriberBuffer a slower subscriber is dropped and has to resume
+	subscriberBuffer = 32
+)
+
+type Event struct {
+	ID             uint64    `json:"id"`
+	Type           string    `json:"type"`
+	Title          string    `json:"title"`
+	URL            string    `json:"url"`
+	Date           string    `json:"date"`
+	Client         string    `json:"client"`
+	SubscriptionID uint      `json:"subscription_id"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type Hub struct {
+	mu          sync.Mutex
+	lastID      uint64
+	subscribers map[uint]map[chan Event]struct{}
+	replay      map[uint][]Event
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		subscribers: make(map[uint]map[chan Event]struct{}),
+		replay:      make(map[uint][]Event),
+	}
+}
+
+// Default the hub used by service and handler
+var Default = NewHub()
+
+// Publish give ev an id and push it to every stream of the user
+func (h *Hub) Publish(userID uint, ev Event) Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// ids keep growing across restarts, so an old Last-Event-ID never hides new events
+	id := uint64(time.Now().UnixMicro())
+	if id <= h.lastID {
+		id = h.lastID + 1
+	}
+	h.lastID = id
+
+	ev.ID = id
+	if ev.CreatedAt.IsZero() {
+		ev.CreatedAt = time.Now()
+	}
+
+	buf := append(h.replay[userID], ev)
+	if len(buf) > replayLimit {
+		buf = buf[len(buf)-replayLimit:]
+	}
+	h.replay[userID] = buf
+
+	for ch := range h.subscribers[userID] {
+		select {
+		case ch <- ev:
+		default:
+			// too slow, let it reconnect with Last-Event-ID
+			delete(h.subscribers[userID], ch)
+			close(ch)
+		}
+	}
+	return ev
+}
+
+// Subscribe events after lastID (0: none) are returned as replay, new ones come from ch.
+// ch is closed when the subscriber is too slow, call cancel when done.
+func (h *Hub) Subscribe(userID uint, lastID uint64) (replay []Event, ch <-chan Event, cancel func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if lastID > 0 {
+		deadline := time.Now().Add(-replayTTL)
+		for _, ev := range h.replay[userID] {
+			if ev.ID > lastID && ev.CreatedAt.After(deadline) {
+				replay = append(replay, ev)
+			}
+		}
+	}
+
+	c := make(chan Event, subscriberBuffer)
+	if h.subscribers[userID] == nil {
+		h.subscribers[userID] = make(map[chan Event]struct{})
+	}
+	h.subscribers[userID][c] = struct{}{}
+
+	cancel = func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if _, ok := h.subscribers[userID][c]; ok {
+			delete(h.subscribers[userID], c)
+			close(c)
+		}
+		if len(h.subscribers[userID]) == 0 {
+			delete(h.subscribers, userID)
+		}
+	}
+	return replay, c, cancel
+}
+
+func Publish(userID uint, ev Event) Event {
+	return Default.Publish(userID, ev)
+}
+
+func Subscribe(userID uint, lastID uint64) ([]Event, <-chan Event, func()) {
+	return Default.Subscribe(userID, lastID)
+}
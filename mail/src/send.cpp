@@ -16,6 +16,7 @@
 #include <getopt.h>
 #include <unistd.h>
 
+#include <algorithm>
 #include <cstring>
 #include <exception>
 #include <filesystem>
@@ -40,7 +41,9 @@ struct Config {
     std::string to = "";
     std::string subject = "";
     std::vector<Attachment> attachments;
+    std::vector<std::string> headers;
     std::string body = "";
+    int retries = 3;
 };
 
 void print_help(const char *prog_name) {
@@ -66,6 +69,10 @@ void print_detail_help(const char *prog_name) {
               << "   -S, --subject                          Subject for the email\n"
               << "   -A, --attachment                       Attachments for the email(e.g.,-A "
                  "1.txt -A 2.txt)\n"
+              << "   -H, --header                           Extra header for the email(e.g.,-H "
+                 "\"List-Unsubscribe: <url>\")\n"
+              << "   -r, --retries <n>                      Attempts in total, 5xx replies are "
+                 "not retried (default 3)\n"
               << "   -h, --help                             Show this help\n";
 }
 
@@ -107,13 +114,15 @@ int main(int argc, char *argv[]) {
                                            {"help", no_argument, 0, 'h'},
                                            {"subject", required_argument, 0, 'S'},
                                            {"attachment", required_argument, 0, 'A'},
+                                           {"header", required_argument, 0, 'H'},
+                                           {"retries", required_argument, 0, 'r'},
                                            {0, 0, 0, 0}};
 
     int opt;
     int option_index = 0;
     opterr = 0;
 
-    while ((opt = getopt_long(argc, argv, "s:u:a:f:t:hS:A:", long_options, &option_index)) != -1) {
+    while ((opt = getopt_long(argc, argv, "s:u:a:f:t:hS:A:H:r:", long_options, &option_index)) != -1) {
         switch (opt) {
             case 's':
                 config.smtp_server = optarg;
@@ -147,6 +156,23 @@ int main(int argc, char *argv[]) {
                 }
                 break;
             }
+            case 'H': {
+                // no header injection
+                std::string header = optarg;
+                header.erase(std::remove_if(header.begin(), header.end(),
+                                            [](char ch) { return ch == '\r' || ch == '\n'; }),
+                             header.end());
+                config.headers.push_back(header);
+                break;
+            }
+            case 'r':
+                try {
+                    config.retries = std::max(1, std::stoi(optarg));
+                } catch (const std::exception &e) {
+                    std::cerr << "invalid retries:" << optarg << '\n';
+                    return 1;
+                }
+                break;
             default:
                 break;
         }
@@ -191,8 +217,11 @@ int main(int argc, char *argv[]) {
     std::stringstream msg;
     msg << "From: " << config.from << "\r\n"
         << "To: " << config.to << "\r\n"
-        << "Subject: " << config.subject << "\r\n"
-        << "MIME-Version: 1.0" << "\r\n"
+        << "Subject: " << config.subject << "\r\n";
+    for (const auto &header : config.headers) {
+        msg << header << "\r\n";
+    }
+    msg << "MIME-Version: 1.0" << "\r\n"
         << "Content-Type: multipart/mixed; boundary=\"" << boundary << "\"\r\n"
         << "\r\n";
 
@@ -245,18 +274,30 @@ int main(int argc, char *argv[]) {
         };
         curl_easy_setopt(curl, CURLOPT_READFUNCTION, +read_callback);
 
+        char error_buf[CURL_ERROR_SIZE];
+        curl_easy_setopt(curl, CURLOPT_ERRORBUFFER, error_buf);
+
         // retry
-        int retries = 3;
+        const std::string mail = payload;
+        int retries = config.retries;
         while (retries > 0) {
+            payload = mail;
+            error_buf[0] = '\0';
             res = curl_easy_perform(curl);
             if (res == CURLE_OK) {
                 std::cout << "Success send！\n";
                 break;
-            } else {
-                std::cerr << "Fail: " << curl_easy_strerror(res) << '\n'
-                          << "Remaining attemps: " << --retries << '\n';
-                if (retries > 0) sleep(10);
             }
+
+            // the last reply of the server, the caller tells permanent failures by it
+            long smtp_code = 0;
+            curl_easy_getinfo(curl, CURLINFO_RESPONSE_CODE, &smtp_code);
+            std::cerr << "Fail: " << curl_easy_strerror(res) << ' ' << error_buf << '\n'
+                      << "SMTP code: " << smtp_code << '\n'
+                      << "Remaining attemps: " << --retries << '\n';
+            // 5xx: trying again will not help
+            if (smtp_code >= 500) break;
+            if (retries > 0) sleep(10);
         }
 
         curl_slist_free_all(recipients);
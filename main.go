@@ -23,6 +23,7 @@ import (
 	"noticat/internal/handler"
 	"noticat/internal/meta"
 	"noticat/internal/scheduler"
+	"noticat/internal/service"
 	"noticat/pkg/global"
 
 	"github.com/gin-gonic/gin"
@@ -32,6 +33,9 @@ func main() {
 	// Init Database
 	global.InitInfrastructure()
 
+	// Start delivery queue
+	service.StartDeliveryWorkers()
+
 	// Start scheduler
 	scheduler.StartScheduler()
 
@@ -41,6 +45,11 @@ func main() {
 	r.POST("/sendcode", handler.SendCodeHandler)
 	r.POST("/register", handler.RegisterHandler)
 	r.POST("/login", handler.LoginHandler)
+	r.GET("/unsubscribe/:token", handler.UnsubscribePageHandler)
+	r.POST("/unsubscribe/:token", handler.UnsubscribeHandler)
+	r.GET("/files/:token", handler.FileHandler)
+	r.GET("/feed/:file", handler.FeedHandler)
+	r.GET("/calendar/:file", handler.CalendarHandler)
 
 	api := r.Group("/api")
 	{
@@ -61,6 +70,40 @@ func main() {
 		api.DELETE("/subscription/:id", handler.DeleteSubscriptionHandler)
 		api.GET("/subscriptions", handler.GetSubscriptionsHandler)
 		api.GET("/subscription/:id", handler.GetSubDetailHandler)
+		api.PUT("/subscription/:id/delivery", handler.UpdateSubDeliveryHandler)
+		api.PUT("/subscription/:id/pause", handler.PauseSubscriptionHandler)
+		api.PUT("/subscription/:id/updates", handler.UpdateSubUpdatesHandler)
+		api.PUT("/subscription/:id/max-age", handler.UpdateSubMaxAgeHandler)
+		api.PUT("/subscription/:id/addresses", handler.UpdateSubAddressesHandler)
+		api.PUT("/subscription/:id/filter/:filter_id/mute", handler.MuteFilterHandler)
+
+		api.GET("/settings", handler.GetSettingsHandler)
+		api.PUT("/settings", handler.UpdateSettingsHandler)
+
+		api.POST("/address/code", handler.SendAddressCodeHandler)
+		api.POST("/address", handler.CreateAddressHandler)
+		api.GET("/addresses", handler.GetAddressesHandler)
+		api.DELETE("/address/:id", handler.DeleteAddressHandler)
+
+		api.POST("/target", handler.CreateTargetHandler)
+		api.GET("/targets", handler.GetTargetsHandler)
+		api.DELETE("/target/:id", handler.DeleteTargetHandler)
+
+		api.GET("/stream", handler.StreamHandler)
+
+		api.GET("/feed", handler.GetFeedHandler)
+		api.POST("/feed/rotate", handler.RotateFeedHandler)
+
+		api.GET("/notices", handler.GetNoticesHandler)
+		api.PATCH("/notices", handler.UpdateNoticesHandler)
+		api.POST("/notices/read-all", handler.ReadAllNoticesHandler)
+		api.GET("/notices/search", handler.SearchNoticesHandler)
+		api.GET("/notice/:id", handler.GetNoticeHandler)
+		api.PATCH("/notice/:id", handler.UpdateNoticeHandler)
+
+		api.GET("/deliveries", handler.GetDeliveriesHandler)
+		api.GET("/deliveries/:id", handler.GetDeliveryDetailHandler)
+		api.POST("/deliveries/:id/retry", handler.RetryDeliveryHandler)
 	}
 
 	r.Run(":" + global.AppPort)
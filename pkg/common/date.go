@@ -0,0 +1,155 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateLayouts after normalizeDate, the ones with a time first
+var dateLayouts = []struct {
+	layout  string
+	hasTime bool
+	// no year, the latest one not after now
+	noYear bool
+}{
+	{"2006-1-2 15:04:05", true, false},
+	{"2006-1-2 15:04", true, false},
+	{"2006-1-2", false, false},
+	{"1-2 15:04:05", true, true},
+	{"1-2 15:04", true, true},
+	{"1-2", false, true},
+}
+
+var (
+	// 3小时前, 5 分钟前, 2天前
+	reAgo = regexp.MustCompile(`^(\d+)\s*(秒|分钟|分|小时|天|周|个月|月|年)之?前$`)
+	// 昨天 12:30, 今天
+	reDayWord = regexp.MustCompile(`^(今天|昨天|前天)\s*(\d{1,2}:\d{2}(?::\d{2})?)?$`)
+	// unix seconds or milliseconds
+	reUnix = regexp.MustCompile(`^\d{10}(\d{3})?$`)
+)
+
+// normalizeDate 2026年1月2日 / 2026/01/02 / 2026.01.02 -> 2026-01-02
+func normalizeDate(s string) string {
+	s = strings.TrimSpace(s)
+	s = strings.NewReplacer(
+		"年", "-", "月", "-", "日", " ",
+		"/", "-", ".", "-", "T", " ",
+	).Replace(s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// ParseDate the date of a notice in loc, hasTime false: only the day is known
+func ParseDate(s string, loc *time.Location) (t time.Time, hasTime bool, ok bool) {
+	return ParseDateAt(s, loc, time.Now())
+}
+
+// ParseDateAt ParseDate with relative dates ("3小时前", "昨天 12:30") and dates
+// without a year ("01-02") taken from now
+func ParseDateAt(s string, loc *time.Location, now time.Time) (t time.Time, hasTime bool, ok bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, false, false
+	}
+	now = now.In(loc)
+
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, true, true
+	}
+	if reUnix.MatchString(s) {
+		n, _ := strconv.ParseInt(s, 10, 64)
+		if len(s) == 13 {
+			return time.UnixMilli(n).In(loc), true, true
+		}
+		return time.Unix(n, 0).In(loc), true, true
+	}
+	if t, hasTime, ok := parseRelative(s, now); ok {
+		return t, hasTime, true
+	}
+
+	norm := normalizeDate(s)
+	for _, l := range dateLayouts {
+		t, err := time.ParseInLocation(l.layout, norm, loc)
+		if err != nil {
+			continue
+		}
+		if l.noYear {
+			t = time.Date(now.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, loc)
+			// "12-31" read on january 1st is last year
+			if t.After(now.Add(24 * time.Hour)) {
+				t = t.AddDate(-1, 0, 0)
+			}
+		}
+		return t, l.hasTime, true
+	}
+	return time.Time{}, false, false
+}
+
+func parseRelative(s string, now time.Time) (time.Time, bool, bool) {
+	switch s {
+	case "刚刚", "刚才":
+		return now, true, true
+	}
+
+	if m := reAgo.FindStringSubmatch(s); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		switch m[2] {
+		case "秒":
+			return now.Add(-time.Duration(n) * time.Second), true, true
+		case "分钟", "分":
+			return now.Add(-time.Duration(n) * time.Minute), true, true
+		case "小时":
+			return now.Add(-time.Duration(n) * time.Hour), true, true
+		case "天":
+			return startOfDay(now.AddDate(0, 0, -n)), false, true
+		case "周":
+			return startOfDay(now.AddDate(0, 0, -7*n)), false, true
+		case "个月", "月":
+			return startOfDay(now.AddDate(0, -n, 0)), false, true
+		case "年":
+			return startOfDay(now.AddDate(-n, 0, 0)), false, true
+		}
+	}
+
+	if m := reDayWord.FindStringSubmatch(s); m != nil {
+		day := startOfDay(now)
+		switch m[1] {
+		case "昨天":
+			day = day.AddDate(0, 0, -1)
+		case "前天":
+			day = day.AddDate(0, 0, -2)
+		}
+		if m[2] == "" {
+			return day, false, true
+		}
+		for _, layout := range []string{"15:04:05", "15:04"} {
+			if clock, err := time.Parse(layout, m[2]); err == nil {
+				return day.Add(time.Duration(clock.Hour())*time.Hour +
+					time.Duration(clock.Minute())*time.Minute +
+					time.Duration(clock.Second())*time.Second), true, true
+			}
+		}
+	}
+	return time.Time{}, false, false
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,162 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDateAt(t *testing.T) {
+	// Sunday 2026-03-15 10:30 in testLoc
+	now := time.Date(2026, 3, 15, 10, 30, 0, 0, testLoc)
+	at := func(y int, m time.Month, d, h, min, s int) time.Time { return time.Date(y, m, d, h, min, s, 0, testLoc) }
+
+	cases := []struct {
+		src     string
+		want    time.Time
+		hasTime bool
+	}{
+		// ISO and its variants
+		{"2026-03-01", at(2026, 3, 1, 0, 0, 0), false},
+		{"2026-3-1", at(2026, 3, 1, 0, 0, 0), false},
+		{"2026/03/01", at(2026, 3, 1, 0, 0, 0), false},
+		{"2026.03.01", at(2026, 3, 1, 0, 0, 0), false},
+		{"  2026-03-01  ", at(2026, 3, 1, 0, 0, 0), false},
+		{"2026-03-01 08:05", at(2026, 3, 1, 8, 5, 0), true},
+		{"2026-03-01 08:05:09", at(2026, 3, 1, 8, 5, 9), true},
+		{"2026-03-01T08:05:09", at(2026, 3, 1, 8, 5, 9), true},
+		// 年月日
+		{"2026年3月1日", at(2026, 3, 1, 0, 0, 0), false},
+		{"2026年03月01日 14:20", at(2026, 3, 1, 14, 20, 0), true},
+		// no year: this year unless that is in the future
+		{"03-01", at(2026, 3, 1, 0, 0, 0), false},
+		{"3-14 09:00", at(2026, 3, 14, 9, 0, 0), true},
+		{"03-16", at(2026, 3, 16, 0, 0, 0), false},
+		{"03-17", at(2025, 3, 17, 0, 0, 0), false},
+		{"12-31 23:59", at(2025, 12, 31, 23, 59, 0), true},
+		// RFC3339 keeps its own offset
+		{"2026-03-01T08:05:09Z", time.Date(2026, 3, 1, 8, 5, 9, 0, time.UTC), true},
+		{"2026-03-01T08:05:09+08:00", at(2026, 3, 1, 8, 5, 9), true},
+		// unix seconds and milliseconds
+		{"1772330709", time.Unix(1772330709, 0), true},
+		{"1772330709123", time.UnixMilli(1772330709123), true},
+		// relative
+		{"刚刚", now, true},
+		{"30秒前", now.Add(-30 * time.Second), true},
+		{"5 分钟前", now.Add(-5 * time.Minute), true},
+		{"3小时前", now.Add(-3 * time.Hour), true},
+		{"2天前", at(2026, 3, 13, 0, 0, 0), false},
+		{"1周前", at(2026, 3, 8, 0, 0, 0), false},
+		{"1个月之前", at(2026, 2, 15, 0, 0, 0), false},
+		{"1年前", at(2025, 3, 15, 0, 0, 0), false},
+		{"今天", at(2026, 3, 15, 0, 0, 0), false},
+		{"昨天 12:30", at(2026, 3, 14, 12, 30, 0), true},
+		{"前天 08:00:05", at(2026, 3, 13, 8, 0, 5), true},
+	}
+	for _, tc := range cases {
+		got, hasTime, ok := ParseDateAt(tc.src, testLoc, now)
+		if !ok {
+			t.Errorf("%q: not parsed", tc.src)
+			continue
+		}
+		if !got.Equal(tc.want) || hasTime != tc.hasTime {
+			t.Errorf("%q = %v hasTime %v, want %v hasTime %v", tc.src, got, hasTime, tc.want, tc.hasTime)
+		}
+	}
+}
+
+func TestParseDateAtYearBoundary(t *testing.T) {
+	// new year's morning, dates without a year are mostly last year's
+	now := time.Date(2026, 1, 1, 9, 0, 0, 0, testLoc)
+	cases := []struct {
+		src  string
+		want time.Time
+	}{
+		{"12-31", time.Date(2025, 12, 31, 0, 0, 0, 0, testLoc)},
+		{"12-30 18:00", time.Date(2025, 12, 30, 18, 0, 0, 0, testLoc)},
+		{"01-01", time.Date(2026, 1, 1, 0, 0, 0, 0, testLoc)},
+		// a day of slack for sites a little ahead
+		{"01-02", time.Date(2026, 1, 2, 0, 0, 0, 0, testLoc)},
+		{"01-03", time.Date(2025, 1, 3, 0, 0, 0, 0, testLoc)},
+		{"昨天", time.Date(2025, 12, 31, 0, 0, 0, 0, testLoc)},
+		{"1个月前", time.Date(2025, 12, 1, 0, 0, 0, 0, testLoc)},
+	}
+	for _, tc := range cases {
+		got, _, ok := ParseDateAt(tc.src, testLoc, now)
+		if !ok || !got.Equal(tc.want) {
+			t.Errorf("%q = %v (%v), want %v", tc.src, got, ok, tc.want)
+		}
+	}
+}
+
+func TestParseDateAtLocation(t *testing.T) {
+	west := time.FixedZone("UTC-5", -5*3600)
+	// 2026-03-15 03:00 UTC is still the 14th in west, already the 15th in testLoc
+	now := time.Date(2026, 3, 15, 3, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		src  string
+		loc  *time.Location
+		want time.Time
+	}{
+		{"2026-03-01", west, time.Date(2026, 3, 1, 5, 0, 0, 0, time.UTC)},
+		{"2026-03-01", testLoc, time.Date(2026, 2, 28, 16, 0, 0, 0, time.UTC)},
+		{"2026-03-01 12:00", west, time.Date(2026, 3, 1, 17, 0, 0, 0, time.UTC)},
+		{"今天", west, time.Date(2026, 3, 14, 5, 0, 0, 0, time.UTC)},
+		{"今天", testLoc, time.Date(2026, 3, 14, 16, 0, 0, 0, time.UTC)},
+		{"03-15", west, time.Date(2026, 3, 15, 5, 0, 0, 0, time.UTC)},
+		{"03-16", west, time.Date(2025, 3, 16, 5, 0, 0, 0, time.UTC)},
+		{"03-16", testLoc, time.Date(2026, 3, 15, 16, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range cases {
+		got, _, ok := ParseDateAt(tc.src, tc.loc, now)
+		if !ok || !got.Equal(tc.want) {
+			t.Errorf("%q in %s = %v (%v), want %v", tc.src, tc.loc, got, ok, tc.want)
+		}
+		if ok && got.Location() != tc.loc {
+			t.Errorf("%q in %s: result in %s", tc.src, tc.loc, got.Location())
+		}
+	}
+
+	// unix times are shown in loc
+	if got, _, _ := ParseDateAt("1772330709", west, now); got.Location() != west {
+		t.Errorf("unix time in %s, want %s", got.Location(), west)
+	}
+}
+
+func TestParseDateAtRejects(t *testing.T) {
+	now := time.Date(2026, 3, 15, 10, 30, 0, 0, testLoc)
+	for _, src := range []string{
+		"",
+		"   ",
+		"未知",
+		"下周一",
+		"3小时后",
+		"几天前",
+		"2026-13-01",
+		"2026-02-30",
+		"13-45",
+		"2026-03-01 25:00",
+		"12345",
+		"177233070912",
+		"明天 10:00",
+		"2026-03",
+	} {
+		if got, hasTime, ok := ParseDateAt(src, testLoc, now); ok {
+			t.Errorf("%q = %v hasTime %v, want not parsed", src, got, hasTime)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import "strings"
+
+// diff ops
+const (
+	DiffEqual  = ' '
+	DiffDelete = '-'
+	DiffInsert = '+'
+)
+
+// DiffLine one line of a line diff
+type DiffLine struct {
+	Op   byte
+	Text string
+}
+
+// texts longer than this (lines a * lines b) are not aligned, all old lines
+// are deleted and all new lines inserted
+const maxDiffCells = 4_000_000
+
+// LineDiff the lines of a and b aligned by their longest common subsequence
+func LineDiff(a, b string) []DiffLine {
+	x := splitLines(a)
+	y := splitLines(b)
+
+	// common head and tail need no table
+	head := 0
+	for head < len(x) && head < len(y) && x[head] == y[head] {
+		head++
+	}
+	tail := 0
+	for tail < len(x)-head && tail < len(y)-head && x[len(x)-1-tail] == y[len(y)-1-tail] {
+		tail++
+	}
+
+	var out []DiffLine
+	for _, l := range x[:head] {
+		out = append(out, DiffLine{DiffEqual, l})
+	}
+	out = append(out, lcsDiff(x[head:len(x)-tail], y[head:len(y)-tail])...)
+	for _, l := range x[len(x)-tail:] {
+		out = append(out, DiffLine{DiffEqual, l})
+	}
+	return out
+}
+
+func lcsDiff(x, y []string) []DiffLine {
+	var out []DiffLine
+	if len(x)*len(y) > maxDiffCells {
+		for _, l := range x {
+			out = append(out, DiffLine{DiffDelete, l})
+		}
+		for _, l := range y {
+			out = append(out, DiffLine{DiffInsert, l})
+		}
+		return out
+	}
+
+	// lcs[i][j]: length of the lcs of x[i:] and y[j:]
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			out = append(out, DiffLine{DiffEqual, x[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out = append(out, DiffLine{DiffDelete, x[i]})
+			i++
+		default:
+			out = append(out, DiffLine{DiffInsert, y[j]})
+			j++
+		}
+	}
+	for ; i < len(x); i++ {
+		out = append(out, DiffLine{DiffDelete, x[i]})
+	}
+	for ; j < len(y); j++ {
+		out = append(out, DiffLine{DiffInsert, y[j]})
+	}
+	return out
+}
+
+// splitLines non-empty trimmed lines
+func splitLines(s string) []string {
+	var lines []string
+	for _, l := range strings.Split(s, "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			lines = append(lines, l)
+		}
+	}
+	return lines
+}
+
+// ChangedLines the changed lines of a diff with up to context unchanged lines
+// around them, nil separates the hunks
+func ChangedLines(diff []DiffLine, context int) []*DiffLine {
+	keep := make([]bool, len(diff))
+	for i, d := range diff {
+		if d.Op == DiffEqual {
+			continue
+		}
+		for k := max(i-context, 0); k <= min(i+context, len(diff)-1); k++ {
+			keep[k] = true
+		}
+	}
+
+	var out []*DiffLine
+	last := -1
+	for i := range diff {
+		if !keep[i] {
+			continue
+		}
+		if last >= 0 && i > last+1 {
+			out = append(out, nil)
+		}
+		out = append(out, &diff[i])
+		last = i
+	}
+	return out
+}
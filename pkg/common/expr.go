@@ -0,0 +1,556 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// fields of an expression, a bare term matches ExprDefaultField
+const (
+	ExprDefaultField = "title"
+	exprMaxLength    = 2000
+	exprMaxDepth     = 32
+)
+
+var (
+	exprTextFields = []string{"title", "url"}
+	exprTimeFields = []string{"date", "deadline"}
+	// -7d, +3d, -12h, -2w
+	reExprOffset = regexp.MustCompile(`^([+-])(\d{1,5})([hdw])$`)
+)
+
+// ExprDoc what an expression is matched against, Time false: unknown
+type ExprDoc interface {
+	Text(field string) string
+	Time(field string) (time.Time, bool)
+}
+
+// ExprError where and why an expression is invalid, Pos counts characters from 0
+type ExprError struct {
+	Pos int
+	Msg string
+}
+
+func (e *ExprError) Error() string {
+	return fmt.Sprintf("第 %d 个字符处: %s", e.Pos+1, e.Msg)
+}
+
+func exprErr(pos int, format string, args ...any) *ExprError {
+	return &ExprError{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Expr a compiled filter expression, e.g.
+//
+//	title:(奖学金 OR 保研) AND NOT title:/已结束/ AND date > -7d
+//
+// terms: word, "exact text", /regexp/, a trailing i on the last two ignores case.
+// a term matches the title unless a field (title:, url:) is given, field:(...)
+// applies to every term inside. date and deadline compare with > >= < <= against
+// a date or an offset from now (-7d, +3d, -12h, -2w), false when the notice has
+// no such date. NOT binds tighter than AND, AND tighter than OR, and terms side
+// by side are ANDed
+type Expr struct {
+	Source string
+	root   exprNode
+}
+
+// CompileExpr parse src once, dates without a zone are in loc
+func CompileExpr(src string, loc *time.Location) (*Expr, error) {
+	runes := []rune(src)
+	if len(runes) > exprMaxLength {
+		return nil, exprErr(exprMaxLength, "表达式过长，最多 %d 个字符", exprMaxLength)
+	}
+	toks, err := lexExpr(runes)
+	if err != nil {
+		return nil, err
+	}
+	if toks[0].kind == tokEOF {
+		return nil, exprErr(0, "表达式为空")
+	}
+
+	p := &exprParser{toks: toks, loc: loc}
+	root, err := p.parseOr(ExprDefaultField)
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		if t.kind == tokRParen {
+			return nil, exprErr(t.pos, "多余的 )")
+		}
+		return nil, p.unexpected(t, "此处需要 AND、OR 或条件")
+	}
+	return &Expr{Source: src, root: root}, nil
+}
+
+// Match evaluate the expression, offsets are taken from now
+func (e *Expr) Match(doc ExprDoc, now time.Time) bool {
+	return e.root.eval(doc, now)
+}
+
+// LegacyFilterExpr the expression equivalent to a keyword or regexp filter
+func LegacyFilterExpr(pattern string, isRegexp bool, ignoreCase bool) string {
+	var b strings.Builder
+	if isRegexp {
+		// escapes stay as they are, only the delimiter is escaped
+		b.WriteByte('/')
+		runes := []rune(pattern)
+		for i := 0; i < len(runes); i++ {
+			switch runes[i] {
+			case '\\':
+				b.WriteRune('\\')
+				if i+1 < len(runes) {
+					i++
+					b.WriteRune(runes[i])
+				}
+			case '/':
+				b.WriteString(`\/`)
+			default:
+				b.WriteRune(runes[i])
+			}
+		}
+		b.WriteByte('/')
+	} else {
+		b.WriteByte('"')
+		b.WriteString(strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(pattern))
+		b.WriteByte('"')
+	}
+	if ignoreCase {
+		b.WriteByte('i')
+	}
+	return b.String()
+}
+
+// ---- lexer ----
+
+type exprTokenKind int
+
+const (
+	tokEOF exprTokenKind = iota
+	tokLParen
+	tokRParen
+	tokAnd
+	tokOr
+	tokNot
+	tokWord
+	tokString
+	tokRegex
+	tokField
+	tokCmp
+)
+
+type exprToken struct {
+	kind exprTokenKind
+	text string
+	// "..."i or /.../i
+	fold bool
+	pos  int
+}
+
+func isExprDelim(r rune) bool {
+	return unicode.IsSpace(r) || strings.ContainsRune(`()":<>=`, r)
+}
+
+func lexExpr(src []rune) ([]exprToken, error) {
+	var toks []exprToken
+	i := 0
+	for i < len(src) {
+		r := src[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			toks = append(toks, exprToken{kind: tokLParen, text: "(", pos: i})
+			i++
+		case r == ')':
+			toks = append(toks, exprToken{kind: tokRParen, text: ")", pos: i})
+			i++
+		case r == '<' || r == '>':
+			start := i
+			i++
+			if i < len(src) && src[i] == '=' {
+				i++
+			}
+			toks = append(toks, exprToken{kind: tokCmp, text: string(src[start:i]), pos: start})
+		case r == '=':
+			return nil, exprErr(i, "不支持的运算符 =，可用 > >= < <=")
+		case r == ':':
+			return nil, exprErr(i, "冒号前缺少字段名")
+		case r == '"' || r == '/':
+			tok, end, err := lexDelimited(src, i)
+			if err != nil {
+				return nil, err
+			}
+			toks = append(toks, tok)
+			i = end
+		default:
+			start := i
+			for i < len(src) && !isExprDelim(src[i]) {
+				i++
+			}
+			word := string(src[start:i])
+			if i < len(src) && src[i] == ':' {
+				i++
+				toks = append(toks, exprToken{kind: tokField, text: word, pos: start})
+				continue
+			}
+			kind := tokWord
+			switch word {
+			case "AND":
+				kind = tokAnd
+			case "OR":
+				kind = tokOr
+			case "NOT":
+				kind = tokNot
+			}
+			toks = append(toks, exprToken{kind: kind, text: word, pos: start})
+		}
+	}
+	return append(toks, exprToken{kind: tokEOF, pos: len(src)}), nil
+}
+
+// lexDelimited a "string" or /regexp/ starting at start. \" and \\ are unescaped
+// in a string, only \/ in a regexp, other escapes are kept
+func lexDelimited(src []rune, start int) (exprToken, int, error) {
+	quote := src[start]
+	tok := exprToken{kind: tokString, pos: start}
+	name := "引号"
+	if quote == '/' {
+		tok.kind = tokRegex
+		name = "正则表达式"
+	}
+
+	var b strings.Builder
+	i := start + 1
+	for {
+		if i >= len(src) {
+			return tok, 0, exprErr(start, "%s没有结束", name)
+		}
+		r := src[i]
+		if r == quote {
+			i++
+			break
+		}
+		if r == '\\' && i+1 < len(src) {
+			next := src[i+1]
+			if next == quote || (next == '\\' && quote == '"') {
+				b.WriteRune(next)
+			} else {
+				b.WriteRune(r)
+				b.WriteRune(next)
+			}
+			i += 2
+			continue
+		}
+		b.WriteRune(r)
+		i++
+	}
+	tok.text = b.String()
+
+	if i < len(src) && src[i] == 'i' {
+		tok.fold = true
+		i++
+	}
+	if i < len(src) && !isExprDelim(src[i]) {
+		return tok, 0, exprErr(i, "%s后需要空格或括号", name)
+	}
+	return tok, i, nil
+}
+
+// ---- parser ----
+
+type exprParser struct {
+	toks  []exprToken
+	i     int
+	loc   *time.Location
+	depth int
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.toks[p.i]
+}
+
+func (p *exprParser) next() exprToken {
+	t := p.toks[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *exprParser) unexpected(t exprToken, want string) *ExprError {
+	switch t.kind {
+	case tokEOF:
+		return exprErr(t.pos, "表达式不完整，%s", want)
+	case tokCmp:
+		return exprErr(t.pos, "%s，得到 %s（只有 %s 可以比较）", want, t.text, strings.Join(exprTimeFields, "、"))
+	case tokField:
+		return exprErr(t.pos, "%s，得到 %s:", want, t.text)
+	}
+	return exprErr(t.pos, "%s，得到 %s", want, t.text)
+}
+
+// or := and (OR and)*
+func (p *exprParser) parseOr(field string) (exprNode, error) {
+	left, err := p.parseAnd(field)
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOr {
+		p.next()
+		right, err := p.parseAnd(field)
+		if err != nil {
+			return nil, err
+		}
+		left = &orNode{left, right}
+	}
+	return left, nil
+}
+
+// and := unary ([AND] unary)*
+func (p *exprParser) parseAnd(field string) (exprNode, error) {
+	left, err := p.parseUnary(field)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch p.peek().kind {
+		case tokAnd:
+			p.next()
+		case tokLParen, tokNot, tokWord, tokString, tokRegex, tokField:
+		default:
+			return left, nil
+		}
+		right, err := p.parseUnary(field)
+		if err != nil {
+			return nil, err
+		}
+		left = &andNode{left, right}
+	}
+}
+
+// unary := NOT unary | primary
+func (p *exprParser) parseUnary(field string) (exprNode, error) {
+	t := p.peek()
+	if t.kind != tokNot {
+		return p.parsePrimary(field)
+	}
+	p.next()
+	if p.depth++; p.depth > exprMaxDepth {
+		return nil, exprErr(t.pos, "嵌套过深")
+	}
+	x, err := p.parseUnary(field)
+	if err != nil {
+		return nil, err
+	}
+	p.depth--
+	return &notNode{x}, nil
+}
+
+// primary := ( or ) | field: term | field:( or ) | timefield cmp value | term
+func (p *exprParser) parsePrimary(field string) (exprNode, error) {
+	t := p.next()
+	switch t.kind {
+	case tokLParen:
+		return p.parseGroup(t, field)
+	case tokField:
+		name := strings.ToLower(t.text)
+		if isExprField(exprTimeFields, name) {
+			return nil, exprErr(t.pos, "%s 是日期字段，需要比较运算符，例如 %s > -7d", name, name)
+		}
+		if !isExprField(exprTextFields, name) {
+			return nil, exprErr(t.pos, "未知字段 %s，可用: %s", t.text,
+				strings.Join(append(append([]string(nil), exprTextFields...), exprTimeFields...), ", "))
+		}
+		v := p.next()
+		switch v.kind {
+		case tokLParen:
+			return p.parseGroup(v, name)
+		case tokWord, tokString, tokRegex:
+			return newTermNode(name, v)
+		}
+		return nil, p.unexpected(v, fmt.Sprintf("%s: 后需要关键词、\"文本\"、/正则/ 或括号", name))
+	case tokWord:
+		if isExprField(exprTimeFields, strings.ToLower(t.text)) && p.peek().kind == tokCmp {
+			return p.parseCompare(t)
+		}
+		return newTermNode(field, t)
+	case tokString, tokRegex:
+		return newTermNode(field, t)
+	}
+	return nil, p.unexpected(t, "此处需要条件")
+}
+
+func (p *exprParser) parseGroup(open exprToken, field string) (exprNode, error) {
+	if p.depth++; p.depth > exprMaxDepth {
+		return nil, exprErr(open.pos, "括号嵌套过深")
+	}
+	x, err := p.parseOr(field)
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokRParen {
+		if t.kind == tokEOF {
+			return nil, exprErr(open.pos, "括号没有闭合")
+		}
+		return nil, p.unexpected(t, "此处需要 )")
+	}
+	p.next()
+	p.depth--
+	return x, nil
+}
+
+func (p *exprParser) parseCompare(fieldTok exprToken) (exprNode, error) {
+	op := p.next()
+	v := p.next()
+	if v.kind != tokWord && v.kind != tokString {
+		return nil, p.unexpected(v, op.text+" 后需要日期，例如 -7d 或 2026-01-02")
+	}
+
+	n := &compareNode{field: strings.ToLower(fieldTok.text), op: op.text, value: v.text, loc: p.loc}
+	if m := reExprOffset.FindStringSubmatch(v.text); m != nil {
+		n.offset, _ = strconv.Atoi(m[2])
+		if m[1] == "-" {
+			n.offset = -n.offset
+		}
+		n.unit = m[3][0]
+	} else if _, _, ok := ParseDate(v.text, p.loc); !ok {
+		return nil, exprErr(v.pos, "无法识别的日期 %s，例如 -7d、+3d、2026-01-02", v.text)
+	}
+	return n, nil
+}
+
+func isExprField(fields []string, name string) bool {
+	for _, f := range fields {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ---- nodes ----
+
+type exprNode interface {
+	eval(doc ExprDoc, now time.Time) bool
+}
+
+type andNode struct{ left, right exprNode }
+
+func (n *andNode) eval(doc ExprDoc, now time.Time) bool {
+	return n.left.eval(doc, now) && n.right.eval(doc, now)
+}
+
+type orNode struct{ left, right exprNode }
+
+func (n *orNode) eval(doc ExprDoc, now time.Time) bool {
+	return n.left.eval(doc, now) || n.right.eval(doc, now)
+}
+
+type notNode struct{ x exprNode }
+
+func (n *notNode) eval(doc ExprDoc, now time.Time) bool {
+	return !n.x.eval(doc, now)
+}
+
+// termNode a text field contains text or matches re
+type termNode struct {
+	field string
+	text  string
+	fold  bool
+	re    *regexp.Regexp
+}
+
+func newTermNode(field string, t exprToken) (exprNode, error) {
+	n := &termNode{field: field, text: t.text, fold: t.fold}
+	if t.kind == tokRegex {
+		pattern := t.text
+		if t.fold {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, exprErr(t.pos, "正则表达式错误: %v", err)
+		}
+		n.re = re
+	} else if n.fold {
+		n.text = strings.ToLower(n.text)
+	}
+	return n, nil
+}
+
+func (n *termNode) eval(doc ExprDoc, _ time.Time) bool {
+	s := doc.Text(n.field)
+	if n.re != nil {
+		return n.re.MatchString(s)
+	}
+	if n.fold {
+		s = strings.ToLower(s)
+	}
+	return strings.Contains(s, n.text)
+}
+
+// compareNode a time field against a date or an offset from now, days and
+// weeks count from the start of the day
+type compareNode struct {
+	field  string
+	op     string
+	value  string
+	loc    *time.Location
+	offset int
+	// 0: value is a date
+	unit byte
+}
+
+func (n *compareNode) bound(now time.Time) (time.Time, bool) {
+	switch n.unit {
+	case 'h':
+		return now.Add(time.Duration(n.offset) * time.Hour), true
+	case 'd':
+		return startOfDay(now.In(n.loc)).AddDate(0, 0, n.offset), true
+	case 'w':
+		return startOfDay(now.In(n.loc)).AddDate(0, 0, 7*n.offset), true
+	}
+	// relative dates like 昨天 follow now
+	t, _, ok := ParseDateAt(n.value, n.loc, now)
+	return t, ok
+}
+
+func (n *compareNode) eval(doc ExprDoc, now time.Time) bool {
+	t, ok := doc.Time(n.field)
+	if !ok {
+		return false
+	}
+	b, ok := n.bound(now)
+	if !ok {
+		return false
+	}
+	switch n.op {
+	case ">":
+		return t.After(b)
+	case ">=":
+		return !t.Before(b)
+	case "<":
+		return t.Before(b)
+	case "<=":
+		return !t.After(b)
+	}
+	return false
+}
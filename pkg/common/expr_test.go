@@ -0,0 +1,243 @@
+// Copyright 2026 Czy_4201b
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var testLoc = time.FixedZone("CST", 8*3600)
+
+// testDoc a notice for the expressions, a zero time is no date
+type testDoc struct {
+	title    string
+	url      string
+	date     time.Time
+	deadline time.Time
+}
+
+func (d testDoc) Text(field string) string {
+	if field == "url" {
+		return d.url
+	}
+	return d.title
+}
+
+func (d testDoc) Time(field string) (time.Time, bool) {
+	t := d.date
+	if field == "deadline" {
+		t = d.deadline
+	}
+	return t, !t.IsZero()
+}
+
+func mustCompile(t *testing.T, src string) *Expr {
+	t.Helper()
+	expr, err := CompileExpr(src, testLoc)
+	if err != nil {
+		t.Fatalf("CompileExpr(%q): %v", src, err)
+	}
+	return expr
+}
+
+func TestExprPrecedence(t *testing.T) {
+	cases := []struct {
+		src   string
+		title string
+		want  bool
+	}{
+		// AND before OR: a OR (b AND c)
+		{"a OR b AND c", "a", true},
+		{"a OR b AND c", "b", false},
+		{"a OR b AND c", "b c", true},
+		{"(a OR b) AND c", "a", false},
+		{"(a OR b) AND c", "a c", true},
+		// NOT before AND: (NOT a) AND b
+		{"NOT a AND b", "b", true},
+		{"NOT a AND b", "a b", false},
+		{"NOT (a AND b)", "a", true},
+		{"NOT NOT a", "a", true},
+		// side by side is AND, still below OR
+		{"a b", "a", false},
+		{"a b", "b a", true},
+		{"a b OR c", "c", true},
+		{"a b OR c", "a", false},
+		{"a NOT b", "a", true},
+		{"a NOT b", "a b", false},
+		// lower case and/or are words
+		{"a or b", "a", false},
+		{"a or b", "a or b", true},
+	}
+	for _, tc := range cases {
+		if got := mustCompile(t, tc.src).Match(testDoc{title: tc.title}, time.Now()); got != tc.want {
+			t.Errorf("%q on %q = %v, want %v", tc.src, tc.title, got, tc.want)
+		}
+	}
+}
+
+func TestExprTerms(t *testing.T) {
+	doc := testDoc{title: "关于 2026 年 Scholarship 评定的通知", url: "https://jwc.example.edu/info/1024.htm"}
+	cases := []struct {
+		src  string
+		want bool
+	}{
+		{"评定", true},
+		{`"年 Scholarship"`, true},
+		{`"scholarship"`, false},
+		{`"scholarship"i`, true},
+		{`/\d{4} 年/`, true},
+		{`/^scholarship/i`, false},
+		{`/schol/i`, true},
+		{"url:jwc", true},
+		{"url:评定", false},
+		{"title:jwc", false},
+		{"URL:jwc", true},
+		{"url:(jwc AND info)", true},
+		{"url:(jwc AND 评定)", false},
+		// the field of the group applies inside only
+		{"url:(jwc) 评定", true},
+		{"url:(news OR /\\/info\\//) AND NOT title:/已结束/", true},
+	}
+	for _, tc := range cases {
+		if got := mustCompile(t, tc.src).Match(doc, time.Now()); got != tc.want {
+			t.Errorf("%q = %v, want %v", tc.src, got, tc.want)
+		}
+	}
+}
+
+func TestExprDates(t *testing.T) {
+	now := time.Date(2026, 3, 15, 10, 30, 0, 0, testLoc)
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 9, 0, 0, 0, testLoc) }
+
+	cases := []struct {
+		src  string
+		doc  testDoc
+		want bool
+	}{
+		// days count from the start of today: -7d is 03-08 00:00
+		{"date > -7d", testDoc{date: day(8)}, true},
+		{"date > -7d", testDoc{date: day(7)}, false},
+		{"date >= -1w", testDoc{date: time.Date(2026, 3, 8, 0, 0, 0, 0, testLoc)}, true},
+		{"date < -1w", testDoc{date: day(7)}, true},
+		// hours count from now
+		{"date > -12h", testDoc{date: day(14)}, false},
+		{"date > -12h", testDoc{date: day(15)}, true},
+		{"deadline < +3d", testDoc{deadline: day(17)}, true},
+		{"deadline < +3d", testDoc{deadline: day(18)}, false},
+		{"deadline <= 2026-03-20", testDoc{deadline: time.Date(2026, 3, 20, 0, 0, 0, 0, testLoc)}, true},
+		{`deadline > "2026-03-20"`, testDoc{deadline: day(19)}, false},
+		// no date: never matches, not even negated comparisons
+		{"date > -7d", testDoc{}, false},
+		{"date <= -7d", testDoc{}, false},
+		{"NOT date > -7d", testDoc{}, true},
+		{"奖学金 AND date >= -30d", testDoc{title: "奖学金", date: day(1)}, true},
+	}
+	for _, tc := range cases {
+		if got := mustCompile(t, tc.src).Match(tc.doc, now); got != tc.want {
+			t.Errorf("%q on %+v = %v, want %v", tc.src, tc.doc, got, tc.want)
+		}
+	}
+}
+
+func TestExprErrors(t *testing.T) {
+	cases := []struct {
+		src string
+		pos int
+	}{
+		{"", 0},
+		{"(a OR b", 0},
+		{"a AND (b OR (c)", 6},
+		{`a "bc`, 2},
+		{`title:/ab`, 6},
+		{"a)", 1},
+		{"a AND", 5},
+		{"foo:bar", 0},
+		{"a OR size:3", 5},
+		{"date:2026", 0},
+		{"a deadline:-3d", 2},
+		{"title > -7d", 6},
+		{"date > soon", 7},
+		{"a = b", 2},
+		{":a", 0},
+		{"/(/", 0},
+		{`"a"b`, 3},
+	}
+	for _, tc := range cases {
+		_, err := CompileExpr(tc.src, testLoc)
+		var exprErr *ExprError
+		if !errors.As(err, &exprErr) {
+			t.Errorf("%q: err = %v, want *ExprError", tc.src, err)
+			continue
+		}
+		if exprErr.Pos != tc.pos {
+			t.Errorf("%q: error at %d (%v), want %d", tc.src, exprErr.Pos, err, tc.pos)
+		}
+	}
+}
+
+// TestLegacyFilterExpr a migrated filter matches what NewFilter matched
+func TestLegacyFilterExpr(t *testing.T) {
+	titles := []string{
+		"关于奖学金评定的通知",
+		"Scholarship 2026",
+		"scholarship 2026",
+		"招生/考试 安排",
+		`路径 C:\temp\a.txt`,
+		`讲座 "AI 与教育"`,
+		"已结束: 奖学金",
+		"",
+	}
+	cases := []struct {
+		pattern    string
+		isRegexp   bool
+		ignoreCase bool
+	}{
+		{"奖学金", false, false},
+		{"Scholarship", false, false},
+		{"Scholarship", false, true},
+		{"招生/考试", false, false},
+		{`C:\temp`, false, false},
+		{`"AI 与教育"`, false, false},
+		{`AND`, false, false},
+		{`^Scholarship \d+$`, true, false},
+		{`^scholarship`, true, true},
+		{`招生/考试`, true, false},
+		{`C:\\temp\\`, true, false},
+		{`"AI`, true, false},
+		{`^(?:已结束|已截止)`, true, false},
+		{`\/`, true, false},
+		{`[/\\]`, true, false},
+	}
+	for _, tc := range cases {
+		old, err := NewFilter(tc.pattern, tc.isRegexp, tc.ignoreCase)
+		if err != nil {
+			t.Fatalf("NewFilter(%q): %v", tc.pattern, err)
+		}
+		src := LegacyFilterExpr(tc.pattern, tc.isRegexp, tc.ignoreCase)
+		expr, err := CompileExpr(src, testLoc)
+		if err != nil {
+			t.Errorf("LegacyFilterExpr(%q) = %s: %v", tc.pattern, src, err)
+			continue
+		}
+		for _, title := range titles {
+			want := old.Match(title)
+			if got := expr.Match(testDoc{title: title}, time.Now()); got != want {
+				t.Errorf("%s on %q = %v, NewFilter(%q) = %v", src, title, got, tc.pattern, want)
+			}
+		}
+	}
+}
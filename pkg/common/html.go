@@ -0,0 +1,186 @@
+package common
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+var blankLinesRe = regexp.MustCompile(`\n{3,}`)
+
+// HTMLToMarkdown turn the detail html from python into markdown for im bots
+func HTMLToMarkdown(s string) string {
+	return convertHTML(s, true)
+}
+
+// HTMLToText turn the detail html into plain text, links become "text (url)"
+func HTMLToText(s string) string {
+	return convertHTML(s, false)
+}
+
+func convertHTML(s string, markdown bool) string {
+	doc, err := html.Parse(strings.NewReader(s))
+	if err != nil {
+		return s
+	}
+
+	var sb strings.Builder
+	var walk func(n *html.Node)
+	walkChildren := func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	childText := func(n *html.Node) string {
+		saved := sb.String()
+		sb.Reset()
+		walkChildren(n)
+		text := strings.TrimSpace(sb.String())
+		sb.Reset()
+		sb.WriteString(saved)
+		return text
+	}
+
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			fields := strings.Fields(n.Data)
+			if len(fields) > 0 && !startsWithSpace(n.Data) {
+				sb.WriteString(strings.Join(fields, " "))
+			} else {
+				writeSpace(&sb)
+				sb.WriteString(strings.Join(fields, " "))
+			}
+			if len(fields) > 0 && endsWithSpace(n.Data) {
+				writeSpace(&sb)
+			}
+			return
+		case html.ElementNode:
+		default:
+			walkChildren(n)
+			return
+		}
+
+		switch n.Data {
+		case "script", "style", "head":
+			return
+		case "br":
+			sb.WriteString("\n")
+		case "p", "div", "section", "article", "table", "ul", "ol", "tr":
+			sb.WriteString("\n")
+			walkChildren(n)
+			sb.WriteString("\n")
+		case "h1", "h2", "h3", "h4", "h5", "h6":
+			text := childText(n)
+			if markdown {
+				sb.WriteString("\n#### " + text + "\n")
+			} else {
+				sb.WriteString("\n" + text + "\n")
+			}
+		case "li":
+			sb.WriteString("\n- ")
+			walkChildren(n)
+		case "td", "th":
+			walkChildren(n)
+			sb.WriteString(" ")
+		case "b", "strong":
+			text := childText(n)
+			if markdown && text != "" {
+				sb.WriteString("**" + text + "**")
+			} else {
+				sb.WriteString(text)
+			}
+		case "a":
+			text := childText(n)
+			href := attr(n, "href")
+			switch {
+			case href == "" || strings.HasPrefix(href, "javascript:"):
+				sb.WriteString(text)
+			case markdown:
+				if text == "" {
+					text = href
+				}
+				sb.WriteString("[" + text + "](" + href + ")")
+			case text == "" || text == href:
+				sb.WriteString(href)
+			default:
+				sb.WriteString(text + " (" + href + ")")
+			}
+		case "img":
+			if src := attr(n, "src"); src != "" && markdown {
+				sb.WriteString("![" + attr(n, "alt") + "](" + src + ")")
+			}
+		default:
+			walkChildren(n)
+		}
+	}
+	walk(doc)
+
+	lines := strings.Split(sb.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	out := strings.Join(lines, "\n")
+	out = blankLinesRe.ReplaceAllString(out, "\n\n")
+	return strings.TrimSpace(out)
+}
+
+func startsWithSpace(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return unicode.IsSpace(r)
+}
+
+func endsWithSpace(s string) bool {
+	r, _ := utf8.DecodeLastRuneInString(s)
+	return unicode.IsSpace(r)
+}
+
+// writeSpace one space between words, never at the start of a line
+func writeSpace(sb *strings.Builder) {
+	s := sb.String()
+	if s == "" || strings.HasSuffix(s, " ") || strings.HasSuffix(s, "\n") {
+		return
+	}
+	sb.WriteString(" ")
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// TruncateBytes cut s to at most max bytes without breaking a utf-8 char,
+// "…" is appended when something is cut
+func TruncateBytes(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	const ellipsis = "…"
+	cut := max - len(ellipsis)
+	if cut <= 0 {
+		return ""
+	}
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + ellipsis
+}
+
+// TruncateRunes cut s to at most max characters, for platforms counting characters
+func TruncateRunes(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	if max <= 1 {
+		return ""
+	}
+	runes := []rune(s)
+	return string(runes[:max-1]) + "…"
+}
@@ -22,6 +22,40 @@ var (
 	SMTPSERVER = getEnv("NOTICAT_SMTP_SERVER", "163")
 	ACCOUNT    = getEnv("NOTICAT_EMAIL_ACCOUNT", "")
 	AUTHCODE   = getEnv("NOTICAT_EMAIL_AUTHCODE", "")
+	// cpp: mail/bin/send, go: internal/mailer
+	MailSender = getEnv("NOTICAT_MAIL_SENDER", "cpp")
+
+	// number of goroutines sending the delivery queue
+	DeliveryWorkers = getEnv("NOTICAT_DELIVERY_WORKERS", "4")
+
+	TelegramAPIBase = getEnv("NOTICAT_TELEGRAM_API", "https://api.telegram.org")
+	// "true": webhook and bot targets may point to loopback / LAN addresses
+	AllowPrivateTargets = getEnv("NOTICAT_ALLOW_PRIVATE_TARGETS", "false")
+
+	// scheduler and users without a timezone
+	TimeZone = getEnv("NOTICAT_TIMEZONE", "Asia/Shanghai")
+
+	// attachment policy, users may override the sizes and the zip mode
+	AttachDownloadMaxMB = getEnv("NOTICAT_ATTACH_DOWNLOAD_MAX_MB", "100")
+	AttachMaxFileMB     = getEnv("NOTICAT_ATTACH_MAX_FILE_MB", "15")
+	AttachMaxTotalMB    = getEnv("NOTICAT_ATTACH_MAX_TOTAL_MB", "20")
+	// off, auto (NOTICAT_ATTACH_ZIP_MIN_FILES or more files) or always
+	AttachZip         = getEnv("NOTICAT_ATTACH_ZIP", "off")
+	AttachZipMinFiles = getEnv("NOTICAT_ATTACH_ZIP_MIN_FILES", "4")
+	// how long the download links of oversized attachments work
+	AttachLinkTTLHours = getEnv("NOTICAT_ATTACH_LINK_TTL_HOURS", "168")
+
+	// content-addressed attachment store, files unused for the retention
+	// days are removed, and the least recently used ones above the quota
+	FileStoreDir      = getEnv("NOTICAT_FILE_STORE", "files")
+	FileRetentionDays = getEnv("NOTICAT_FILE_RETENTION_DAYS", "30")
+	FileStoreQuotaMB  = getEnv("NOTICAT_FILE_QUOTA_MB", "2048")
+
+	// base of the links in mails (unsubscribe...), no trailing slash
+	PublicURL = getEnv("NOTICAT_PUBLIC_URL", "http://localhost:8080")
+
+	// how often the detail of a delivered notice is fetched again to find edits
+	UpdateCheckMinutes = getEnv("NOTICAT_UPDATE_CHECK_MINUTES", "60")
 
 	RedisAddr = getEnv("REDIS_ADDR", "localhost:6379")
 	AppPort = getEnv("APP_PORT", "8080")
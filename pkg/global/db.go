@@ -7,12 +7,15 @@ package global
 import (
 	"context"
 	"fmt"
+	"log"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"noticat/internal/model"
+	"noticat/internal/search"
+	"noticat/pkg/common"
 )
 
 func InitInfrastructure() {
@@ -29,7 +32,9 @@ func InitInfrastructure() {
 	}
 
 	// 自动迁移表结构
-	DB.AutoMigrate(&model.User{}, &model.UserSubscription{}, &model.SubscriptionFilter{}, &model.UserNotice{}, &model.FetchTask{})
+	DB.AutoMigrate(&model.User{}, &model.UserSubscription{}, &model.SubscriptionFilter{}, &model.UserNotice{}, &model.FetchTask{}, &model.NotifyTarget{}, &model.PendingNotice{}, &model.DeliveryJob{}, &model.DeliveryAttempt{}, &model.UserAddress{}, &model.SubscriptionAddress{}, &model.StoredFile{}, &model.NoticeFile{}, &model.Notice{})
+	search.Init(DB)
+	migrateFilterExpressions()
 
 	// --- 2. 初始化 Redis ---
 	RDB = redis.NewClient(&redis.Options{
@@ -47,3 +52,26 @@ func InitInfrastructure() {
 
 	fmt.Println("🚀 数据库与 Redis 初始化成功！")
 }
+
+// migrateFilterExpressions convert the keyword and regex filters saved before
+// expressions existed
+func migrateFilterExpressions() {
+	var filters []model.SubscriptionFilter
+	if err := DB.Where("expression = '' OR expression IS NULL").Find(&filters).Error; err != nil {
+		log.Printf("[Filter] 查询待迁移的过滤规则失败: %v", err)
+		return
+	}
+	for _, f := range filters {
+		expr := f.Pattern
+		if f.Type != "expr" {
+			expr = common.LegacyFilterExpr(f.Pattern, f.Type == "regex", f.IgnoreCase)
+		}
+		if err := DB.Model(&f).Update("expression", expr).Error; err != nil {
+			log.Printf("[Filter] 迁移过滤规则 %d 失败: %v", f.ID, err)
+			return
+		}
+	}
+	if len(filters) > 0 {
+		log.Printf("[Filter] 已将 %d 条过滤规则转换为表达式", len(filters))
+	}
+}
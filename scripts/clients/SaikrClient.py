@@ -50,7 +50,12 @@ class SaikrClient(BaseClient):
             origin_url = contest["contest_url"]
             full_url = f"https://www.saikr.com/{origin_url}"
 
-            result.append({"title": clean_title, "url": full_url, "date": full_date})
+            # registration deadline, for the calendar feed
+            deadline = ""
+            if contest.get("regist_end_time"):
+                deadline = time.strftime("%Y-%m-%d %H:%M", time.localtime(contest["regist_end_time"]))
+
+            result.append({"title": clean_title, "url": full_url, "date": full_date, "deadline": deadline})
 
         return result
 